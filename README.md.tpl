@@ -127,3 +127,10 @@ func ExampleKS() {{ "ExampleKS" | code }}
 
 func ExampleKV() {{ "ExampleKV" | code }}
 ```
+
+### Key/values
+Key/values are passed to loggers as `models.KeyValue`, which has a `Type`
+for values written as JSON numbers or booleans, and can be computed lazily
+with `j.KVFunc`. This is a breaking change for code creating key/values with
+positional literals, like `models.KeyValue{"k", "v"}`, which must name the
+fields instead: `models.KeyValue{Key: "k", Value: "v"}`.
@@ -0,0 +1,36 @@
+package errors
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var clock atomic.Pointer[func() time.Time]
+
+// SetClock sets the function used to timestamp errors created by New and
+// Wrap, it defaults to time.Now. Passing nil restores the default.
+func SetClock(now func() time.Time) {
+	if now == nil {
+		clock.Store(nil)
+		return
+	}
+	clock.Store(&now)
+}
+
+// SetClockTesting sets the clock for the duration of the test.
+func SetClockTesting(t testing.TB, now func() time.Time) {
+	old := clock.Load()
+	t.Cleanup(func() {
+		clock.Store(old)
+	})
+	SetClock(now)
+}
+
+func now() time.Time {
+	c := clock.Load()
+	if c == nil {
+		return time.Now()
+	}
+	return (*c)()
+}
@@ -0,0 +1,66 @@
+package errors
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/peterlabuschagne/jettison/internal"
+)
+
+// CodeGenerator returns the code for an error created with the message msg at
+// the given file and line, see SetCodeGenerator.
+type CodeGenerator func(msg, file string, line int) string
+
+var codeGenerator atomic.Pointer[CodeGenerator]
+
+// SetCodeGenerator sets a function to generate the codes of errors created
+// without one by New, NewSentinel, Newf, NewKV and Wrap, e.g. from a hash of
+// the file and line, so that every error has a unique code without setting
+// one with WithCode. The file and line are where the error was created,
+// taking WithSkip into account. Wrap with an empty message doesn't add an
+// error, so it never generates a code.
+//
+// Since Is matches errors with the same code, errors created at the same
+// place match each other, as if they were created with the same WithCode.
+// Passing nil restores the default, which is to leave the code empty, so
+// that the message is used instead, see GetCodes.
+func SetCodeGenerator(fn CodeGenerator) {
+	if fn == nil {
+		codeGenerator.Store(nil)
+		return
+	}
+	codeGenerator.Store(&fn)
+}
+
+// SetCodeGeneratorTesting sets the code generator for the duration of the test.
+func SetCodeGeneratorTesting(t testing.TB, fn CodeGenerator) {
+	old := codeGenerator.Load()
+	t.Cleanup(func() {
+		codeGenerator.Store(old)
+	})
+	SetCodeGenerator(fn)
+}
+
+// generateCode sets the code of je with the generator set by SetCodeGenerator
+// if it doesn't have one. skip is the number of frames to skip above the
+// caller of generateCode, see getSource for how ol changes the caller.
+// Errors without a message, like sentinels wrapped with an empty message,
+// don't get a code.
+func generateCode(je *internal.Error, skip int, ol []Option) {
+	gen := codeGenerator.Load()
+	if gen == nil || je.Code != "" || je.Message == "" {
+		return
+	}
+	var (
+		file string
+		line int
+	)
+	if pc, ok := callerPC(ol); ok {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		file, line = frame.File, frame.Line
+	} else {
+		_, file, line, _ = runtime.Caller(skip + 1 + callerSkip(ol))
+	}
+	je.Code = (*gen)(je.Message, file, line)
+}
@@ -0,0 +1,98 @@
+package errors_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/peterlabuschagne/jettison/errors"
+	"github.com/peterlabuschagne/jettison/internal"
+)
+
+func hashCode(_, file string, line int) string {
+	h := sha256.Sum256([]byte(filepath.Base(file) + ":" + strconv.Itoa(line)))
+	return "E" + hex.EncodeToString(h[:4])
+}
+
+func codeOf(err error) string {
+	return err.(*internal.Error).Code
+}
+
+func newAtSameSite() error {
+	return errors.New("same site")
+}
+
+func TestSetCodeGenerator(t *testing.T) {
+	errors.SetCodeGeneratorTesting(t, hashCode)
+
+	t.Run("different sites", func(t *testing.T) {
+		a := errors.New("same message")
+		b := errors.New("same message")
+		assert.NotEmpty(t, codeOf(a))
+		assert.NotEmpty(t, codeOf(b))
+		assert.NotEqual(t, codeOf(a), codeOf(b))
+		assert.False(t, errors.Is(a, b))
+	})
+
+	t.Run("same site is stable", func(t *testing.T) {
+		a, b := newAtSameSite(), newAtSameSite()
+		assert.Equal(t, codeOf(a), codeOf(b))
+		assert.True(t, errors.Is(a, b))
+	})
+
+	t.Run("explicit code", func(t *testing.T) {
+		err := errors.New("msg", errors.WithCode("explicit"))
+		assert.Equal(t, "explicit", codeOf(err))
+	})
+
+	t.Run("constructors", func(t *testing.T) {
+		for _, err := range []error{
+			errors.NewSentinel("sentinel"),
+			errors.Newf("newf %d", 1),
+			errors.NewKV("kv"),
+			errors.Wrap(io.EOF, "wrap"),
+		} {
+			assert.Regexp(t, "^E[0-9a-f]{8}$", codeOf(err))
+		}
+	})
+
+	t.Run("wrap", func(t *testing.T) {
+		base := errors.New("base")
+		err := errors.Wrap(base, "wrap")
+		codes := errors.GetCodes(err)
+		assert.Len(t, codes, 2)
+		assert.NotEqual(t, codes[0], codes[1])
+		assert.Equal(t, codeOf(base), codes[1])
+
+		// Wrapping without a message doesn't add a code
+		err = errors.Wrap(errors.NewSentinel("sentinel", errors.WithCode("sentinel")), "")
+		assert.Equal(t, []string{"sentinel"}, errors.GetCodes(err))
+	})
+
+	t.Run("with skip", func(t *testing.T) {
+		newInHelper := func() error {
+			return errors.New("helper", errors.WithSkip(1))
+		}
+		var line int
+		errors.SetCodeGeneratorTesting(t, func(_, _ string, l int) string {
+			line = l
+			return "code"
+		})
+		_, _, callerLine, _ := runtime.Caller(0)
+		newInHelper()
+		assert.Equal(t, callerLine+1, line)
+	})
+
+	t.Run("default", func(t *testing.T) {
+		errors.SetCodeGeneratorTesting(t, nil)
+		err := errors.New("msg")
+		assert.Empty(t, codeOf(err))
+		assert.Equal(t, []string{"msg"}, errors.GetCodes(err))
+	})
+}
@@ -0,0 +1,102 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peterlabuschagne/jettison/internal"
+	"github.com/peterlabuschagne/jettison/models"
+)
+
+// dumpTraceFrames is the number of stack frames shown for each error by Dump.
+const dumpTraceFrames = 5
+
+// Dump returns a human-readable description of the err error tree for
+// debugging. Every error in the tree is shown with its binary, message, code,
+// key/value pairs and the first few frames of its stack trace, indented
+// under the error which wraps it. Joined errors are listed one after another
+// at the same depth.
+//
+// Unlike Error, which is meant for users, the output of Dump is only meant
+// for people and its format may change.
+func Dump(err error) string {
+	if err == nil {
+		return "<nil>\n"
+	}
+	var sb strings.Builder
+	dumpRecur(&sb, err, 0)
+	return sb.String()
+}
+
+func dumpRecur(sb *strings.Builder, err error, depth int) {
+	for err != nil {
+		indent := strings.Repeat("  ", depth)
+		dumpError(sb, indent, err)
+
+		switch unw := err.(type) {
+		case interface{ Unwrap() error }:
+			err = unw.Unwrap()
+		case interface{ Unwrap() []error }:
+			errs := unw.Unwrap()
+			fmt.Fprintf(sb, "%s  joined: %d errors\n", indent, len(errs))
+			for _, e := range errs {
+				dumpRecur(sb, e, depth+2)
+			}
+			return
+		default:
+			return
+		}
+		depth++
+	}
+}
+
+func dumpError(sb *strings.Builder, indent string, err error) {
+	je, ok := err.(*internal.Error)
+	if !ok {
+		if _, isJoin := err.(interface{ Unwrap() []error }); isJoin {
+			// The message of a join repeats the messages of the joined errors
+			fmt.Fprintf(sb, "%s- %T\n", indent, err)
+		} else {
+			fmt.Fprintf(sb, "%s- %T: %q\n", indent, err, errorMessage(err))
+		}
+		return
+	}
+	fmt.Fprintf(sb, "%s- %q\n", indent, je.Message)
+	if je.Binary != "" {
+		fmt.Fprintf(sb, "%s  binary: %s\n", indent, je.Binary)
+	}
+	if je.Code != "" {
+		fmt.Fprintf(sb, "%s  code: %s\n", indent, je.Code)
+	}
+	if je.Source != "" {
+		fmt.Fprintf(sb, "%s  source: %s\n", indent, je.Source)
+	}
+	if len(je.KV) > 0 {
+		kvs := make([]string, 0, len(je.KV))
+		for _, kv := range models.ResolveAll(je.KV) {
+			kvs = append(kvs, kv.Key+"="+kv.Value)
+		}
+		fmt.Fprintf(sb, "%s  kvs: %s\n", indent, strings.Join(kvs, ", "))
+	}
+	if len(je.StackTrace) > 0 {
+		fmt.Fprintf(sb, "%s  trace:\n", indent)
+		for i, frame := range je.StackTrace {
+			if i == dumpTraceFrames {
+				fmt.Fprintf(sb, "%s    ... %d more\n", indent, len(je.StackTrace)-i)
+				break
+			}
+			fmt.Fprintf(sb, "%s    %s\n", indent, frame)
+		}
+	}
+}
+
+// errorMessage returns the part of err's message which isn't repeated by the
+// error it wraps, if any, so that wrapped messages aren't shown twice.
+func errorMessage(err error) string {
+	msg := err.Error()
+	unw, ok := err.(interface{ Unwrap() error })
+	if !ok || unw.Unwrap() == nil {
+		return msg
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(msg, unw.Unwrap().Error()), ": ")
+}
@@ -0,0 +1,30 @@
+package errors_test
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/sebdah/goldie/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/peterlabuschagne/jettison/errors"
+	"github.com/peterlabuschagne/jettison/j"
+)
+
+func TestDump(t *testing.T) {
+	errors.SetTraceConfigTesting(t, errors.TestingConfig)
+
+	dbErr := errors.Wrap(fmt.Errorf("query: %w", io.EOF), "lookup user",
+		j.C("db_error"), j.KV("user", "1"))
+	cacheErr := errors.New("cache miss", j.C("cache_miss"),
+		errors.WithSource("cache.go", 12))
+	err := errors.Join(dbErr, cacheErr)
+	err = errors.Wrap(err, "get profile", j.KV("service", "profile"))
+
+	goldie.New(t).Assert(t, t.Name(), []byte(errors.Dump(err)))
+}
+
+func TestDumpNil(t *testing.T) {
+	assert.Equal(t, "<nil>\n", errors.Dump(nil))
+}
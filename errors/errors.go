@@ -1,9 +1,18 @@
 package errors
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	stderrors "errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/peterlabuschagne/jettison/internal"
+	"github.com/peterlabuschagne/jettison/models"
 )
 
 type ErrorOption func(je *internal.Error)
@@ -21,20 +30,182 @@ func WithStackTrace() Option {
 	})
 }
 
+// WithStackTraceDepth limits the stack trace of this error to at most n frames,
+// keeping the frames nearest to where the error was created.
+// If n is zero or negative, the stack trace is not limited.
+// It must be provided after WithStackTrace to limit that trace.
+func WithStackTraceDepth(n int) Option {
+	return ErrorOption(func(je *internal.Error) {
+		if n > 0 && len(je.StackTrace) > n {
+			je.StackTrace = je.StackTrace[:n]
+		}
+	})
+}
+
 // WithCode sets an error code on the error. A code should uniquely identity an error,
 // the intention being to provide an equality check for jettison errors (see Is() for more details).
-// The default code (the error message) doesn't provide strong unique guarantees.
+// The default code (the error message) doesn't provide strong unique guarantees,
+// see SetCodeGenerator to generate codes for errors without one.
 func WithCode(code string) Option {
 	return ErrorOption(func(je *internal.Error) {
 		je.Code = code
 	})
 }
 
+// WithSource overrides the source code reference recorded on the error with
+// the given file and line. This is useful for errors created in helper
+// functions, where the source would otherwise point at the helper.
+//
+//	func fail(msg string) error {
+//	  _, file, line, _ := runtime.Caller(1)
+//	  return errors.New(msg, errors.WithSource(file, line))
+//	}
+func WithSource(file string, line int) Option {
+	return ErrorOption(func(je *internal.Error) {
+		je.Source = fmt.Sprintf("%s:%d", file, line)
+	})
+}
+
+// WithoutSource clears the source of the error, it complements
+// WithoutStackTrace. New and Wrap don't look up the source when given this
+// option, which saves some work when wrapping errors in hot paths.
+func WithoutSource() Option {
+	return withoutSource{}
+}
+
+type withoutSource struct{}
+
+func (withoutSource) ApplyToError(je *internal.Error) {
+	je.Source = ""
+}
+
+// WithSkip skips n more stack frames when New or Wrap look up the source and
+// stack trace of the error, so that errors created in helper functions point
+// at the helper's caller. Without it, the source and the first frame of the
+// trace are the function calling New or Wrap, WithSkip(1) makes them its
+// caller, and so on. Several WithSkip options add up. The trace captured by
+// WithStackTrace isn't affected.
+//
+//	func fail(ctx context.Context, msg string) error {
+//	  return errors.New(msg, errors.WithSkip(1)) // The source is fail's caller
+//	}
+func WithSkip(n int) Option {
+	return withSkip(n)
+}
+
+type withSkip int
+
+func (withSkip) ApplyToError(*internal.Error) {}
+
+// atPC is the option added by WrapAt to record the error at a program counter
+// rather than where it was created.
+type atPC uintptr
+
+func (atPC) ApplyToError(*internal.Error) {}
+
+// WithKV adds a key/value pair to the error. The pair is logged as part of the
+// error's parameters and survives being sent over gRPC.
+func WithKV(key, value string) Option {
+	return ErrorOption(func(je *internal.Error) {
+		je.KV = append(je.KV, models.KeyValue{Key: key, Value: value})
+	})
+}
+
+// WithKVs adds multiple key/value pairs to the error, taking alternating keys
+// and values. A trailing key without a value is added with an empty value.
+//
+//	errors.Wrap(err, "transfer failed", errors.WithKVs("account_id", id, "attempt", "3"))
+func WithKVs(keyValues ...string) Option {
+	return ErrorOption(func(je *internal.Error) {
+		for i := 0; i < len(keyValues); i += 2 {
+			kv := models.KeyValue{Key: keyValues[i]}
+			if i+1 < len(keyValues) {
+				kv.Value = keyValues[i+1]
+			}
+			je.KV = append(je.KV, kv)
+		}
+	})
+}
+
+// WithKeyValues adds the key/value pairs to the error, in the order given,
+// e.g. when they're built dynamically. It can be combined with other key/value
+// options, like j.KV, and the pairs of each option are added in turn.
+//
+//	errors.Wrap(err, "transfer failed", errors.WithKeyValues(kvs...))
+func WithKeyValues(kvs ...models.KeyValue) Option {
+	return ErrorOption(func(je *internal.Error) {
+		je.KV = append(je.KV, kvs...)
+	})
+}
+
+// WithRetryable marks the error as retryable, see IsRetryable.
+func WithRetryable() Option {
+	return ErrorOption(func(je *internal.Error) {
+		je.Retryable = true
+	})
+}
+
+// WithSeverity sets the level that log.Error logs the error at, e.g. "warn"
+// for expected errors which shouldn't alert. The severity should be one of the
+// log package's levels, see Severity.
+func WithSeverity(level string) Option {
+	return ErrorOption(func(je *internal.Error) {
+		je.Severity = level
+	})
+}
+
+// WithTags adds free-form labels to the error, e.g. "transient" or "payment",
+// for filtering logs. Unlike a code, which identifies an error and is used by
+// Is, an error can have any number of tags and they aren't used for matching.
+// Tags are logged and are sent with the error over JSON and gRPC.
+func WithTags(tags ...string) Option {
+	return ErrorOption(func(je *internal.Error) {
+		// Limit the capacity so that the tags of copies made by Wrap are
+		// never shared
+		je.Tags = append(je.Tags[:len(je.Tags):len(je.Tags)], tags...)
+	})
+}
+
+// WithMetadata attaches v to the error so that it can be retrieved with As,
+// e.g. a struct describing the request which failed. Values are matched by
+// type, if an error has several values of the same type the last one is used.
+//
+//	err := errors.Wrap(err, "charge failed", errors.WithMetadata(billing))
+//	...
+//	var b BillingContext
+//	if errors.As(err, &b) {
+//	  ...
+//	}
+//
+// Metadata isn't logged and doesn't survive JSON encoding or gRPC.
+func WithMetadata(v any) Option {
+	return ErrorOption(func(je *internal.Error) {
+		je.Metadata = append(je.Metadata[:len(je.Metadata):len(je.Metadata)], v)
+	})
+}
+
+// WithHTTPStatus sets the HTTP status code to respond with for the error,
+// see HTTPStatus.
+func WithHTTPStatus(code int) Option {
+	return ErrorOption(func(je *internal.Error) {
+		je.HTTPStatus = code
+	})
+}
+
+// WithExitCode sets the exit code a command line tool should exit with for
+// the error, see ExitCode. An exit code of 0 is the same as not setting one.
+func WithExitCode(code int) Option {
+	return ErrorOption(func(je *internal.Error) {
+		je.ExitCode = code
+	})
+}
+
 // WithoutStackTrace clears any automatically populated stack trace.
 // New always populates a stack trace and Wrap will if no sub error has a trace.
 //
 // This Option is useful for sentinel errors which have a useless init-time stack trace.
-// Removing it allows a stacktrace to be added when it is Wrapped.
+// Removing it allows a stacktrace to be added when it is Wrapped. NewSentinel
+// does the same.
 //
 // Example
 //
@@ -65,39 +236,290 @@ type Option interface {
 
 // New creates a new JettisonError with a populated stack trace
 func New(msg string, ol ...Option) error {
+	if len(ol) == 0 {
+		// Without options, the calls for the source and trace can be
+		// collected once
+		je := &internal.Error{
+			Message:   msg,
+			Timestamp: now(),
+		}
+		je.Source, je.Binary, je.StackTrace = getSourceAndTrace(1)
+		generateCode(je, 1, nil)
+		return je
+	}
 	je := &internal.Error{
-		Message: msg,
-		Source:  getSourceCode(1),
+		Message:   msg,
+		Source:    getSource(1, ol),
+		Timestamp: now(),
+	}
+	je.Binary, je.StackTrace = getCallerTrace(1, ol)
+	for _, o := range ol {
+		o.ApplyToError(je)
+	}
+	je.KV = internal.TruncateValues(je.KV)
+	generateCode(je, 1, ol)
+	return je
+}
+
+// NewSentinel creates a new JettisonError without a stack trace, for sentinel
+// errors declared at init time. It's the same as New with WithoutStackTrace,
+// so a stack trace is added when the error is wrapped.
+//
+//	var ErrFoo = errors.NewSentinel("foo", errors.WithCode("foo"))
+func NewSentinel(msg string, ol ...Option) error {
+	je := &internal.Error{
+		Message:   msg,
+		Source:    getSource(1, ol),
+		Timestamp: now(),
 	}
-	je.Binary, je.StackTrace = getTrace(1)
 	for _, o := range ol {
 		o.ApplyToError(je)
 	}
+	je.KV = internal.TruncateValues(je.KV)
+	je.Binary, je.StackTrace = "", nil
+	generateCode(je, 1, ol)
+	return je
+}
+
+// Newf creates a new JettisonError with a populated stack trace and a
+// message formatted with fmt.Sprintf.
+func Newf(format string, args ...any) error {
+	je := &internal.Error{
+		Message:   fmt.Sprintf(format, args...),
+		Source:    getSourceCode(1),
+		Timestamp: now(),
+	}
+	je.Binary, je.StackTrace = getTrace(1)
+	generateCode(je, 1, nil)
+	return je
+}
+
+// NewKV creates a new JettisonError with a populated stack trace and the given
+// key/value pairs, in the order given. It's the same as passing the pairs to
+// New as j.KV or j.MKV options.
+//
+//	errors.NewKV("transfer failed", models.KeyValue{Key: "account_id", Value: id})
+func NewKV(msg string, kvs ...models.KeyValue) error {
+	je := &internal.Error{
+		Message:   msg,
+		Source:    getSourceCode(1),
+		Timestamp: now(),
+	}
+	je.Binary, je.StackTrace = getTrace(1)
+	if len(kvs) > 0 {
+		je.KV = internal.TruncateValues(append([]models.KeyValue(nil), kvs...))
+	}
+	generateCode(je, 1, nil)
 	return je
 }
 
 // Wrap will wrap an existing error in a new JettisonError.
 // If no error in the err error tree has a trace, a stack trace is populated.
+//
+// If msg is empty and err is a JettisonError with a stack trace, no new error
+// is added to the chain. Instead, the options are applied to a copy of err,
+// which still matches err with Is, or err is returned as is if the options
+// don't change it. Sentinel errors without a stack trace are wrapped as
+// usual, so that they get one.
+//
+// The length of the chain can be limited with SetMaxHops.
 func Wrap(err error, msg string, ol ...Option) error {
 	if err == nil {
 		return nil
 	}
+	if je, ok := err.(*internal.Error); ok && msg == "" {
+		if _, _, found := GetLastStackTrace(je); found {
+			return applyToCopy(je, ol)
+		}
+	}
 	je := &internal.Error{
-		Message: msg,
-		Err:     err,
-		Source:  getSourceCode(1),
+		Message:   msg,
+		Err:       err,
+		Source:    getSource(1, ol),
+		Timestamp: now(),
 	}
 	// We only need to add a trace when wrapping sentinel or non-jettison errors
 	// for the first time
 	if _, _, found := GetLastStackTrace(err); !found {
-		je.Binary, je.StackTrace = getTrace(1)
+		je.Binary, je.StackTrace = getCallerTrace(1, ol)
 	}
 	for _, o := range ol {
 		o.ApplyToError(je)
 	}
-	return je
+	je.KV = internal.TruncateValues(je.KV)
+	generateCode(je, 1, ol)
+	return limitHops(je)
+}
+
+// applyToCopy applies the options of Wrap with an empty message to a copy of
+// je, or returns je if they don't change it.
+func applyToCopy(je *internal.Error, ol []Option) error {
+	changed := false
+	for _, o := range ol {
+		switch o.(type) {
+		case withSkip, atPC, withoutSource:
+			// These only apply to the source and trace, which je already has
+		default:
+			changed = true
+		}
+	}
+	if !changed {
+		// There's nothing to change, so je can be shared
+		return je
+	}
+	// A shallow copy is enough since the slices of je are only ever
+	// replaced, never modified in place
+	c := je.Copy()
+	// Key values from the options come before the existing ones,
+	// as if they had been added by wrapping
+	kvs := c.KV
+	c.KV = nil
+	for _, o := range ol {
+		o.ApplyToError(c)
+	}
+	if len(c.KV) == 0 {
+		c.KV = kvs
+	} else {
+		c.KV = internal.TruncateValues(append(c.KV, kvs...))
+	}
+	return c
+}
+
+// WrapAt is like Wrap, but records the source, the first frame of the stack
+// trace and the generated code at the program counter pc rather than at the
+// caller of WrapAt. This is useful for helpers which defer wrapping errors
+// until after the call they describe has returned. The pc is obtained with
+// runtime.Callers, which also skips itself and the function calling it:
+//
+//	var pcs [1]uintptr
+//	runtime.Callers(2, pcs[:]) // The caller of the function calling Callers
+//	...
+//	return errors.WrapAt(err, "query failed", pcs[0])
+//
+// If pc isn't on the stack, e.g. because the function it was taken in has
+// returned, the source and stack trace are those of the caller of WrapAt,
+// as with Wrap, and WithSkip applies to them.
+func WrapAt(err error, msg string, pc uintptr, ol ...Option) error {
+	return Wrap(err, msg, append(ol[:len(ol):len(ol)], atPC(pc), WithSkip(1))...)
+}
+
+// Must returns v if err is nil, otherwise it panics with err wrapped as by
+// Wrap with an empty message, so that errors without a stack trace get one
+// from where Must is called. It's intended for initialisation code and tests,
+// where an error can't be handled.
+//
+//	var tmpl = errors.Must(template.New("email").Parse(email))
+func Must[T any](v T, err error) T {
+	if err != nil {
+		panic(Wrap(err, "", WithSkip(1)))
+	}
+	return v
+}
+
+// Must0 is like Must, for functions which only return an error.
+//
+//	errors.Must0(db.Ping())
+func Must0(err error) {
+	if err != nil {
+		panic(Wrap(err, "", WithSkip(1)))
+	}
 }
 
+// WrapWith is like Wrap, but also applies innerOpts to a copy of the deepest
+// JettisonError in err, for details which belong to the origin of the error
+// rather than where it's wrapped, e.g. a code for an error from a library.
+// outerOpts are applied to the new error as by Wrap.
+//
+//	err = errors.WrapWith(err, "charge card",
+//	  []errors.Option{errors.C("card_declined")}, j.KV("card_id", id))
+//
+// For joined errors, the deepest JettisonError along the first joined error
+// is used, as by UnwrapAll. If err doesn't contain a JettisonError, the error
+// added by Wrap is the deepest, so both sets of options are applied to it,
+// with outerOpts applied last.
+func WrapWith(err error, msg string, innerOpts []Option, outerOpts ...Option) error {
+	if err == nil {
+		return nil
+	}
+	var deepest *internal.Error
+	for _, e := range UnwrapAll(err) {
+		if je, ok := e.(*internal.Error); ok {
+			deepest = je
+		}
+	}
+	if deepest == nil {
+		ol := make([]Option, 0, len(innerOpts)+len(outerOpts)+1)
+		ol = append(append(append(ol, innerOpts...), outerOpts...), WithSkip(1))
+		return Wrap(err, msg, ol...)
+	}
+	if len(innerOpts) > 0 {
+		err = Map(err, func(e error) error {
+			if e != error(deepest) {
+				return nil
+			}
+			// Options append to the slices of the error, which are
+			// shared by a shallow copy
+			c := deepest.Clone()
+			for _, o := range innerOpts {
+				o.ApplyToError(c)
+			}
+			c.KV = internal.TruncateValues(c.KV)
+			return c
+		})
+	}
+	return Wrap(err, msg, append(outerOpts[:len(outerOpts):len(outerOpts)], WithSkip(1))...)
+}
+
+// Annotate adds key/value pairs to err without adding a message, for when
+// context about an error is learnt but there's nothing to add to its message.
+// The pairs are added to a copy of the most recent JettisonError in err,
+// before its existing pairs, so that they take precedence in GetKeyValues.
+// Errors which aren't JettisonErrors are wrapped in one with an empty
+// message. Annotate returns err if it's nil or there are no pairs.
+//
+// Unlike Wrap(err, "", WithKV(k, v)), Annotate never looks up a stack trace
+// or source, even if err doesn't have one.
+func Annotate(err error, kvs ...models.KeyValue) error {
+	if err == nil || len(kvs) == 0 {
+		return err
+	}
+	kvs = internal.TruncateValues(kvs)
+	je, ok := err.(*internal.Error)
+	if !ok {
+		return &internal.Error{
+			Err:       err,
+			KV:        append([]models.KeyValue(nil), kvs...),
+			Timestamp: now(),
+		}
+	}
+	c := *je
+	c.KV = make([]models.KeyValue, 0, len(kvs)+len(je.KV))
+	c.KV = append(append(c.KV, kvs...), je.KV...)
+	return &c
+}
+
+// Code is an error which matches any JettisonError with the same code when
+// used as the target of Is, which also works for errors received over gRPC.
+//
+//	if errors.Is(err, errors.Code("payment_declined")) {
+//	  ...
+//	}
+type Code = internal.Code
+
+// JettisonError is the type of errors created by this package, e.g. by New
+// and Wrap. Use As to get the outermost JettisonError in an error tree, which
+// also finds errors wrapped by other errors or joined with Join:
+//
+//	var je *errors.JettisonError
+//	if errors.As(err, &je) {
+//	  log.Println(je.Code, je.StackTrace)
+//	}
+//
+// Err is the error wrapped by the JettisonError. Errors received over gRPC
+// are rebuilt as JettisonErrors, so errors of other types in the original
+// tree aren't available, and Err is nil for the deepest error.
+type JettisonError = internal.Error
+
 // Is is an alias of the standard library's errors.Is() function.
 func Is(err, target error) bool {
 	return stderrors.Is(err, target)
@@ -115,18 +537,587 @@ func IsAny(err error, targets ...error) bool {
 }
 
 // As is an alias of the standard library's errors.As() function.
+// It also finds values attached to JettisonErrors with WithMetadata, which
+// unlike the standard library's As, allows target to point to any type.
 func As(err error, target any) bool {
-	return stderrors.As(err, target)
+	typ := reflect.TypeOf(target)
+	if typ == nil || typ.Kind() != reflect.Pointer || typ.Elem().Kind() == reflect.Interface ||
+		typ.Elem().Implements(errorType) {
+		return stderrors.As(err, target)
+	}
+	// The standard library panics for targets which aren't errors,
+	// so only look for metadata
+	var found bool
+	Walk(err, func(err error) bool {
+		if a, ok := err.(interface{ As(any) bool }); ok && a.As(target) {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
 }
 
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
 // Unwrap is an alias of the standard library's errors.Unwrap() function.
 func Unwrap(err error) error {
 	return stderrors.Unwrap(err)
 }
 
-// Join is an alias of the standard library's errors.Join() function.
-func Join(err ...error) error {
-	return stderrors.Join(err...)
+// Cause returns the deepest error in err's chain which isn't a JettisonError,
+// following both JettisonErrors and standard library Unwrap methods. This is
+// usually the error returned by another library, which can then be inspected
+// with a type switch. For joined errors only the first joined error is
+// followed. Cause returns nil if there is no such error, e.g. when err was
+// created by New.
+func Cause(err error) error {
+	var cause error
+	for err != nil {
+		switch unw := err.(type) {
+		case *internal.Error:
+			err = unw.Err
+		case interface{ Unwrap() []error }:
+			errs := unw.Unwrap()
+			if len(errs) == 0 {
+				return err
+			}
+			err = errs[0]
+		default:
+			cause = err
+			err = stderrors.Unwrap(err)
+		}
+	}
+	return cause
+}
+
+// Map returns a copy of the err error tree with errors replaced by fn, e.g. to
+// translate errors from another library at a package boundary.
+//
+//	err = errors.Map(err, func(err error) error {
+//	  if err == sql.ErrNoRows {
+//	    return ErrNotFound
+//	  }
+//	  return nil
+//	})
+//
+// fn is called for each error in the tree, starting with err. If it returns
+// nil the error is kept, and fn is called for the errors it wraps. Otherwise,
+// the error and the errors it wraps are replaced by the returned error, so fn
+// must wrap the error passed to it to keep them.
+// The errors wrapping a replaced error are kept, with the message, code and
+// other details of JettisonErrors unchanged. Other errors wrapping a replaced
+// error can't be copied, so they are replaced by a JettisonError with the
+// same message. For joined errors, fn is applied to each of them.
+// Map returns err if no errors are replaced.
+func Map(err error, fn func(error) error) error {
+	if err == nil {
+		return nil
+	}
+	if r := fn(err); r != nil {
+		return r
+	}
+	switch unw := err.(type) {
+	case *internal.Error:
+		next := Map(unw.Err, fn)
+		if next == unw.Err {
+			return err
+		}
+		c := *unw
+		c.Err = next
+		return &c
+	case interface{ Unwrap() []error }:
+		errs := unw.Unwrap()
+		mapped := make([]error, len(errs))
+		var changed bool
+		for i, e := range errs {
+			mapped[i] = Map(e, fn)
+			changed = changed || mapped[i] != e
+		}
+		if !changed {
+			return err
+		}
+		return stderrors.Join(mapped...)
+	case interface{ Unwrap() error }:
+		next := Map(unw.Unwrap(), fn)
+		if next == unw.Unwrap() {
+			return err
+		}
+		return &internal.Error{
+			Message:   errorMessage(err),
+			Err:       next,
+			Timestamp: now(),
+		}
+	}
+	return err
+}
+
+// ReplaceCode returns a copy of the err error tree with the code of every
+// jettison error with oldCode replaced by newCode, e.g. to translate internal
+// codes to public ones at a service boundary. Unlike WithCode, no error is
+// added to the tree, and codes deep in the tree are replaced. Use
+// ReplaceFirstCode to only replace the outermost match.
+//
+//	err = errors.ReplaceCode(err, "db_timeout", "unavailable")
+//
+// Errors are copied as by Map, and err is returned if no codes match.
+func ReplaceCode(err error, oldCode, newCode string) error {
+	var replace func(error) error
+	replace = func(err error) error {
+		je, ok := err.(*internal.Error)
+		if !ok || je.Code != oldCode {
+			return nil
+		}
+		c := *je
+		c.Code = newCode
+		c.Err = Map(je.Err, replace)
+		return &c
+	}
+	return Map(err, replace)
+}
+
+// ReplaceFirstCode is like ReplaceCode, but only replaces the code of the
+// first jettison error with oldCode, in the order visited by Walk.
+func ReplaceFirstCode(err error, oldCode, newCode string) error {
+	var replaced bool
+	return Map(err, func(err error) error {
+		je, ok := err.(*internal.Error)
+		if replaced || !ok || je.Code != oldCode {
+			return nil
+		}
+		replaced = true
+		c := *je
+		c.Code = newCode
+		return &c
+	})
+}
+
+// FilterOption removes details from the errors copied by Filter. It's applied
+// to a shallow copy of each JettisonError, so it must replace the slices of
+// the error rather than modify them.
+type FilterOption func(je *internal.Error)
+
+// FilterKeys removes the key/values with keys starting with prefix.
+func FilterKeys(prefix string) FilterOption {
+	return func(je *internal.Error) {
+		var kvs []models.KeyValue
+		for _, kv := range je.KV {
+			if !strings.HasPrefix(kv.Key, prefix) {
+				kvs = append(kvs, kv)
+			}
+		}
+		je.KV = kvs
+	}
+}
+
+// FilterStackTraces removes stack traces and binaries.
+func FilterStackTraces() FilterOption {
+	return func(je *internal.Error) {
+		je.Binary, je.StackTrace = "", nil
+	}
+}
+
+// Filter returns a copy of the err error tree with details removed from every
+// JettisonError by the options, e.g. to remove internal key/values before an
+// error leaves a service. err isn't modified.
+//
+//	err = errors.Filter(err, errors.FilterKeys("_internal"), errors.FilterStackTraces())
+//
+// Only the details of errors are removed, the structure of the tree is the
+// same, so Flatten returns the same paths for the copy as for err. Errors are
+// copied as by Map. Note that wrapping an error without stack traces adds one.
+func Filter(err error, opts ...FilterOption) error {
+	if len(opts) == 0 {
+		return err
+	}
+	var filter func(error) error
+	filter = func(err error) error {
+		je, ok := err.(*internal.Error)
+		if !ok {
+			return nil
+		}
+		c := *je
+		for _, o := range opts {
+			o(&c)
+		}
+		c.Err = Map(je.Err, filter)
+		return &c
+	}
+	return Map(err, filter)
+}
+
+// UnwrapAll returns err and every error it wraps, from outermost to innermost,
+// so that the type of each error can be inspected. For joined errors, the
+// join is included and only the first joined error is followed, use Flatten
+// to get every path through the tree. UnwrapAll returns nil if err is nil.
+func UnwrapAll(err error) []error {
+	var ret []error
+	for err != nil {
+		if len(ret) >= internal.CycleCheckDepth && internal.OnPath(ret, err) {
+			break
+		}
+		ret = append(ret, err)
+		if unw, ok := err.(interface{ Unwrap() []error }); ok {
+			errs := unw.Unwrap()
+			if len(errs) == 0 {
+				break
+			}
+			err = errs[0]
+			continue
+		}
+		err = stderrors.Unwrap(err)
+	}
+	return ret
+}
+
+// Equal reports whether two error trees are equivalent, ignoring details
+// which differ between runs. Jettison errors are compared by Message, Code
+// and KV, StackTrace, Source, Binary, Retryable, HTTPStatus, ExitCode,
+// Severity, Tags, Timestamp and metadata are ignored.
+// Other errors are equal if they are the same error, or have the same type
+// and message. The wrapped and joined errors are compared in the same way.
+func Equal(a, b error) bool {
+	return equal(a, b, internal.Path{}, internal.Path{})
+}
+
+// equal is Equal, with the paths to a and b so that it terminates even if
+// they have cycles.
+func equal(a, b error, pathA, pathB internal.Path) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	var newA, newB bool
+	pathA, newA = pathA.Visit(a)
+	pathB, newB = pathB.Visit(b)
+	if !newA && !newB {
+		// Both are cycles, which are equal up to here
+		return true
+	}
+	ja, okA := a.(*internal.Error)
+	jb, okB := b.(*internal.Error)
+	if okA != okB {
+		return false
+	}
+	if okA {
+		if ja.Message != jb.Message || ja.Code != jb.Code || !equalKVs(ja.KV, jb.KV) {
+			return false
+		}
+		return equal(ja.Err, jb.Err, pathA, pathB)
+	}
+	if a == b {
+		return true
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || a.Error() != b.Error() {
+		return false
+	}
+	switch unwA := a.(type) {
+	case interface{ Unwrap() error }:
+		return equal(unwA.Unwrap(), b.(interface{ Unwrap() error }).Unwrap(), pathA, pathB)
+	case interface{ Unwrap() []error }:
+		errsA := unwA.Unwrap()
+		errsB := b.(interface{ Unwrap() []error }).Unwrap()
+		if len(errsA) != len(errsB) {
+			return false
+		}
+		for i := range errsA {
+			if !equal(errsA[i], errsB[i], pathA, pathB) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func equalKVs(a, b []models.KeyValue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// EqualUnordered reports whether two error trees contain the same errors,
+// regardless of how they are nested, for tests where the order of wrapping
+// differs, e.g. for errors received over gRPC.
+//
+// Each JettisonError is compared by its Message, Code and KV, with the order
+// of the key/value pairs ignored. Other errors are compared by the result of
+// their Error method, except for joins which are ignored. Errors with an
+// empty message, code and key/values are also ignored. The trees are equal if
+// they contain the same number of each error. Like Equal, the other fields,
+// e.g. StackTrace and Source, are ignored.
+func EqualUnordered(a, b error) bool {
+	ca, cb := errorCounts(a), errorCounts(b)
+	if len(ca) != len(cb) {
+		return false
+	}
+	for k, n := range ca {
+		if cb[k] != n {
+			return false
+		}
+	}
+	return true
+}
+
+// errorCounts returns the number of each error compared by EqualUnordered in
+// the err error tree.
+func errorCounts(err error) map[string]int {
+	ret := make(map[string]int)
+	Walk(err, func(err error) bool {
+		var (
+			msg, code string
+			kvs       []string
+		)
+		if je, ok := err.(*internal.Error); ok {
+			msg, code = je.Message, je.Code
+			for _, kv := range models.ResolveAll(je.KV) {
+				kvs = append(kvs, strconv.Quote(kv.Key)+"="+strconv.Quote(kv.Value))
+			}
+			sort.Strings(kvs)
+		} else if _, isJoin := err.(interface{ Unwrap() []error }); !isJoin {
+			msg = err.Error()
+		}
+		if msg == "" && code == "" && len(kvs) == 0 {
+			return true
+		}
+		ret[strconv.Quote(msg)+" "+strconv.Quote(code)+" "+strings.Join(kvs, ",")]++
+		return true
+	})
+	return ret
+}
+
+// Join returns a JettisonError wrapping the standard library's errors.Join()
+// of the given errors, nil errors are discarded. Join returns nil if every
+// error is nil.
+//
+// A stack trace is populated unless every joined error already has one.
+// Is, As, Walk and Flatten descend into each of the joined errors.
+func Join(errs ...error) error {
+	return join(errs, 1)
+}
+
+// join is Join, with the source and trace of the caller `skip` frames above
+// the caller of join.
+func join(errs []error, skip int) error {
+	joined := stderrors.Join(errs...)
+	if joined == nil {
+		return nil
+	}
+	je := &internal.Error{
+		Err:       joined,
+		Source:    getSourceCode(skip + 1),
+		Timestamp: now(),
+	}
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if _, _, found := GetLastStackTrace(err); !found {
+			je.Binary, je.StackTrace = getTrace(skip + 1)
+			break
+		}
+	}
+	return je
+}
+
+// WrapAll wraps several errors with a single message, e.g. the errors
+// collected by a function which carries on after the first one fails.
+// Nil errors are discarded and the others are joined as by Join, so that
+// each is still matched by Is and logged separately, and the join is
+// wrapped with msg and the options as by Wrap. WrapAll returns nil if every
+// error is nil, and only wraps the error without a join if there's one.
+//
+//	var errs []error
+//	for _, id := range ids {
+//	  errs = append(errs, process(ctx, id))
+//	}
+//	return errors.WrapAll(errs, "process failed")
+func WrapAll(errs []error, msg string, ol ...Option) error {
+	var nonNil []error
+	for _, err := range errs {
+		if err != nil {
+			nonNil = append(nonNil, err)
+		}
+	}
+	ol = append(ol[:len(ol):len(ol)], WithSkip(1))
+	switch len(nonNil) {
+	case 0:
+		return nil
+	case 1:
+		return Wrap(nonNil[0], msg, ol...)
+	}
+	return Wrap(join(nonNil, callerSkip(ol)), msg, ol...)
+}
+
+// Combine returns a copy of the err error tree with structurally identical
+// joined errors merged, so that errors wrapped along several branches of a
+// join are only logged once, with a single stack trace.
+//
+// Joined errors are structurally identical if they have the same chain of
+// messages and codes, i.e. they and the errors they wrap have the same
+// messages and codes in the same order, including any errors they join.
+// Errors other than JettisonErrors must also have the same type.
+// The first of the identical errors is kept, with the key/values of the others
+// added to it, and their stack traces used where it has none.
+// Combine returns err if no errors are merged.
+func Combine(err error) error {
+	switch unw := err.(type) {
+	case *internal.Error:
+		next := Combine(unw.Err)
+		if next == unw.Err {
+			return err
+		}
+		c := *unw
+		c.Err = next
+		return &c
+	case interface{ Unwrap() []error }:
+		var (
+			merged  []error
+			changed bool
+		)
+		// index maps structure keys to the merged errors
+		index := make(map[string]int)
+		for _, e := range unw.Unwrap() {
+			c := Combine(e)
+			changed = changed || c != e
+			key := structureKey(c)
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(merged)
+				merged = append(merged, c)
+				continue
+			}
+			merged[i] = mergeErrors(merged[i], c)
+			changed = true
+		}
+		if !changed {
+			return err
+		}
+		return stderrors.Join(merged...)
+	case interface{ Unwrap() error }:
+		next := Combine(unw.Unwrap())
+		if next == unw.Unwrap() {
+			return err
+		}
+		return &internal.Error{
+			Message:   errorMessage(err),
+			Err:       next,
+			Timestamp: now(),
+		}
+	}
+	return err
+}
+
+// structureKey returns a string identifying the chain of messages and codes
+// of err, so that structurally identical errors have the same key.
+func structureKey(err error) string {
+	var sb strings.Builder
+	for err != nil {
+		switch unw := err.(type) {
+		case *internal.Error:
+			sb.WriteString(strconv.Quote(unw.Message) + " " + strconv.Quote(unw.Code) + ";")
+			err = unw.Err
+		case interface{ Unwrap() []error }:
+			sb.WriteString("join(")
+			for _, e := range unw.Unwrap() {
+				sb.WriteString(structureKey(e) + ",")
+			}
+			sb.WriteString(")")
+			return sb.String()
+		case interface{ Unwrap() error }:
+			sb.WriteString(fmt.Sprintf("%T %q;", err, errorMessage(err)))
+			err = unw.Unwrap()
+		default:
+			sb.WriteString(fmt.Sprintf("%T %q;", err, err.Error()))
+			return sb.String()
+		}
+	}
+	return sb.String()
+}
+
+// mergeErrors merges b into a, which have the same structureKey.
+func mergeErrors(a, b error) error {
+	switch ua := a.(type) {
+	case *internal.Error:
+		ub, ok := b.(*internal.Error)
+		if !ok {
+			return a
+		}
+		c := *ua
+		for _, kv := range ub.KV {
+			if !containsKV(c.KV, kv) {
+				c.KV = append(c.KV[:len(c.KV):len(c.KV)], kv)
+			}
+		}
+		if len(c.StackTrace) == 0 {
+			c.Binary, c.StackTrace = ub.Binary, ub.StackTrace
+		}
+		c.Err = mergeErrors(ua.Err, ub.Err)
+		return &c
+	case interface{ Unwrap() []error }:
+		ub, ok := b.(interface{ Unwrap() []error })
+		if !ok {
+			return a
+		}
+		errsA, errsB := ua.Unwrap(), ub.Unwrap()
+		if len(errsA) != len(errsB) {
+			return a
+		}
+		merged := make([]error, len(errsA))
+		for i := range errsA {
+			merged[i] = mergeErrors(errsA[i], errsB[i])
+		}
+		return stderrors.Join(merged...)
+	}
+	return a
+}
+
+// Fingerprint returns a stable hash identifying the logical error, so that
+// instances of the same error can be grouped, e.g. for alerting. Two errors
+// have the same fingerprint if their trees have the same shape and, in the
+// same order:
+//   - JettisonErrors have the same code, or the same message if they have no
+//     code, and the same keys, in any order.
+//   - Other errors have the same type, and the same message if they don't
+//     wrap another error.
+//
+// Values of key/value pairs, stack traces, sources and binaries are ignored,
+// so they don't change the fingerprint between instances or builds.
+// Since messages of errors without codes are used, errors with variable
+// messages, e.g. from Newf, should have codes. It returns an empty string
+// for nil errors.
+func Fingerprint(err error) string {
+	if err == nil {
+		return ""
+	}
+	h := sha256.New()
+	Walk(err, func(err error) bool {
+		switch e := err.(type) {
+		case *internal.Error:
+			id := e.Code
+			if id == "" {
+				id = "msg:" + e.Message
+			}
+			keys := make([]string, 0, len(e.KV))
+			for _, kv := range e.KV {
+				keys = append(keys, kv.Key)
+			}
+			sort.Strings(keys)
+			fmt.Fprintf(h, "jettison %q %q\n", id, keys)
+		case interface{ Unwrap() []error }:
+			fmt.Fprintf(h, "join %T %d\n", err, len(e.Unwrap()))
+		case interface{ Unwrap() error }:
+			fmt.Fprintf(h, "wrap %T\n", err)
+		default:
+			fmt.Fprintf(h, "error %T %q\n", err, err.Error())
+		}
+		return true
+	})
+	return hex.EncodeToString(h.Sum(nil)[:8])
 }
 
 // GetCodes returns the stack of error codes in the given jettison error chain.
@@ -150,6 +1141,247 @@ func GetCodes(err error) []string {
 	return ret
 }
 
+// GetMessages returns the messages of the JettisonErrors in the given error
+// chain, skipping empty messages. The messages are returned in
+// reverse-order of calls to Wrap(), i.e. the message of the latest wrapped
+// error comes first in the list. Messages of non-jettison errors are not
+// included.
+func GetMessages(err error) []string {
+	var ret []string
+	Walk(err, func(err error) bool {
+		je, ok := err.(*internal.Error)
+		if ok && je.Message != "" {
+			ret = append(ret, je.Message)
+		}
+		return true
+	})
+	return ret
+}
+
+// GetTags returns the tags added using WithTags to any error in the err error
+// tree, without duplicates. The tags of the latest wrapped error come first.
+func GetTags(err error) []string {
+	var ret []string
+	seen := make(map[string]bool)
+	Walk(err, func(err error) bool {
+		je, ok := err.(*internal.Error)
+		if !ok {
+			return true
+		}
+		for _, t := range je.Tags {
+			if !seen[t] {
+				seen[t] = true
+				ret = append(ret, t)
+			}
+		}
+		return true
+	})
+	return ret
+}
+
+// GetTimestamps returns when each JettisonError in the err error tree was
+// created by New or wrapped by Wrap, see SetClock. The timestamps are returned
+// in reverse-order of calls to Wrap(), i.e. the latest wrap comes first.
+func GetTimestamps(err error) []time.Time {
+	var ret []time.Time
+	Walk(err, func(err error) bool {
+		je, ok := err.(*internal.Error)
+		if ok && !je.Timestamp.IsZero() {
+			ret = append(ret, je.Timestamp)
+		}
+		return true
+	})
+	return ret
+}
+
+// GetBinaries returns the distinct binaries which added stack traces to the
+// err error tree, e.g. the services an error passed through over gRPC.
+// Unlike GetCodes, the binaries are ordered from where the error originated
+// to the most recent, so the first binary is the error's origin.
+func GetBinaries(err error) []string {
+	var bins []string
+	Walk(err, func(err error) bool {
+		je, ok := err.(*internal.Error)
+		if ok && je.Binary != "" {
+			bins = append(bins, je.Binary)
+		}
+		return true
+	})
+	var ret []string
+	for i := len(bins) - 1; i >= 0; i-- {
+		if !contains(ret, bins[i]) {
+			ret = append(ret, bins[i])
+		}
+	}
+	return ret
+}
+
+// IsCode returns true if any jettison error in the err error tree has the
+// given code. Unlike Is, this doesn't rely on the identity of sentinel errors
+// so it can be used to match errors which have been sent over gRPC. Unlike
+// GetCodes, messages of errors without codes are not treated as codes, so an
+// empty code never matches.
+//
+//	if errors.IsCode(err, "not_found") {
+//	  return nil, status.Error(codes.NotFound, "not found")
+//	}
+func IsCode(err error, code string) bool {
+	if code == "" {
+		return false
+	}
+	var found bool
+	Walk(err, func(err error) bool {
+		je, ok := err.(*internal.Error)
+		if ok && je.Code == code {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// HasCode returns true if any jettison error in the chain of wrapped errors
+// has exactly the given code, stopping at the first match. Unlike IsCode, it
+// only follows errors wrapping a single error, so it doesn't match the codes
+// of errors joined with Join. Unlike GetCodes, messages of errors without
+// codes are not treated as codes, so an empty code never matches. It returns
+// false for nil and non-jettison errors.
+//
+//	if errors.HasCode(err, "not_found") {
+//	  return nil, status.Error(codes.NotFound, "not found")
+//	}
+func HasCode(err error, code string) bool {
+	if code == "" {
+		return false
+	}
+	var path internal.Path
+	for err != nil {
+		var ok bool
+		if path, ok = path.Visit(err); !ok {
+			return false
+		}
+		if je, ok := err.(*internal.Error); ok && je.Code == code {
+			return true
+		}
+		unw, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return false
+		}
+		err = unw.Unwrap()
+	}
+	return false
+}
+
+// IsRetryable returns true if any jettison error in the err error tree
+// was marked as retryable using WithRetryable. An error is retryable if any
+// error in the tree says so, regardless of where it is in the chain.
+//
+// JettisonErrors also have a Temporary method, like net.Error, which returns
+// the same as IsRetryable for the error.
+func IsRetryable(err error) bool {
+	var found bool
+	Walk(err, func(err error) bool {
+		je, ok := err.(*internal.Error)
+		if ok && je.Retryable {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// HTTPStatus returns the HTTP status code set using WithHTTPStatus in the err
+// error tree. If more than one error has a status code, the status code of the
+// latest wrapped error is returned.
+//
+//	if status, ok := errors.HTTPStatus(err); ok {
+//	  w.WriteHeader(status)
+//	}
+func HTTPStatus(err error) (int, bool) {
+	var status int
+	Walk(err, func(err error) bool {
+		je, ok := err.(*internal.Error)
+		if ok && je.HTTPStatus != 0 {
+			status = je.HTTPStatus
+			return false
+		}
+		return true
+	})
+	return status, status != 0
+}
+
+// ExitCode returns the exit code set using WithExitCode in the err error tree.
+// If more than one error has an exit code, the exit code of the latest
+// wrapped error is returned.
+func ExitCode(err error) (int, bool) {
+	var code int
+	Walk(err, func(err error) bool {
+		je, ok := err.(*internal.Error)
+		if ok && je.ExitCode != 0 {
+			code = je.ExitCode
+			return false
+		}
+		return true
+	})
+	return code, code != 0
+}
+
+// ExitCodeOr returns the exit code set using WithExitCode in the err error
+// tree like ExitCode, or fallback if there is none. It returns 0 if err is
+// nil.
+//
+//	if err := run(); err != nil {
+//	  log.Error(ctx, err)
+//	  os.Exit(errors.ExitCodeOr(err, 1))
+//	}
+func ExitCodeOr(err error, fallback int) int {
+	if err == nil {
+		return 0
+	}
+	if code, ok := ExitCode(err); ok {
+		return code
+	}
+	return fallback
+}
+
+// Severity returns the severity set using WithSeverity in the err error tree.
+// If more than one error has a severity, the severity of the latest wrapped
+// error is returned.
+func Severity(err error) (string, bool) {
+	var severity string
+	Walk(err, func(err error) bool {
+		je, ok := err.(*internal.Error)
+		if ok && je.Severity != "" {
+			severity = je.Severity
+			return false
+		}
+		return true
+	})
+	return severity, severity != ""
+}
+
+// StackTrace returns the stack trace of the error which originated err, i.e.
+// the deepest error in the chain with a stack trace, unlike GetLastStackTrace
+// which returns the most recent one. For errors sent over gRPC, this is the
+// trace from the binary which created the error. Only the first of any
+// joined errors is followed, as with UnwrapAll. The frames are formatted as
+// set by SetTraceConfig, and the returned slice may be modified by the
+// caller. It returns nil if no error in the chain has a stack trace.
+func StackTrace(err error) []string {
+	var trace []string
+	for _, e := range UnwrapAll(err) {
+		if je, ok := e.(*internal.Error); ok && len(je.StackTrace) > 0 {
+			trace = je.StackTrace
+		}
+	}
+	if trace == nil {
+		return nil
+	}
+	return append([]string(nil), trace...)
+}
+
 func GetLastStackTrace(err error) (string, []string, bool) {
 	var bin string
 	var stack []string
@@ -167,7 +1399,9 @@ func GetLastStackTrace(err error) (string, []string, bool) {
 	return bin, stack, found
 }
 
-// GetKeyValues returns all embedded key value info in the error
+// GetKeyValues returns all embedded key value info in the error.
+// If a key is present more than once in the chain, the value from the most
+// recently wrapped error is used. See GetKeyValueList to retain duplicates.
 func GetKeyValues(err error) map[string]string {
 	ret := make(map[string]string)
 	Walk(err, func(err error) bool {
@@ -177,7 +1411,7 @@ func GetKeyValues(err error) map[string]string {
 				if _, ok := ret[kv.Key]; ok {
 					continue
 				}
-				ret[kv.Key] = kv.Value
+				ret[kv.Key] = kv.Resolve().Value
 			}
 		}
 		return true
@@ -185,15 +1419,45 @@ func GetKeyValues(err error) map[string]string {
 	return ret
 }
 
+// GetKeyValueList returns all embedded key values in the error chain in wrap
+// order, i.e. the key values of the latest wrapped error come first in the list.
+// Duplicate keys from different errors are preserved. It returns nil if no
+// error in the chain has key values.
+func GetKeyValueList(err error) []models.KeyValue {
+	var ret []models.KeyValue
+	Walk(err, func(err error) bool {
+		je, ok := err.(*internal.Error)
+		if ok {
+			ret = append(ret, models.ResolveAll(je.KV)...)
+		}
+		return true
+	})
+	return ret
+}
+
+// WalkFunc is called by Walk for each error in the tree, returning false
+// terminates the traversal.
+type WalkFunc func(error) bool
+
 // Walk will do a depth first traversal of the error tree.
 // do is called for each error on the traversal, if it returns false,
 // then the traversal will be terminated
-func Walk(err error, do func(error) bool) {
-	walkRecur(err, do)
+//
+// Errors are visited before the errors they wrap and joined errors are
+// visited in order, so the visit order is the same as the paths returned by
+// Flatten. Walk terminates even if err has a cycle, which can only be created
+// by modifying an error after wrapping it, by not descending into errors
+// already on the path to the error being visited.
+func Walk(err error, do WalkFunc) {
+	walkRecur(err, do, internal.Path{})
 }
 
-func walkRecur(err error, do func(error) bool) bool {
+func walkRecur(err error, do WalkFunc, path internal.Path) bool {
 	for err != nil {
+		var ok bool
+		if path, ok = path.Visit(err); !ok {
+			return true
+		}
 		if !do(err) {
 			return false
 		}
@@ -205,7 +1469,7 @@ func walkRecur(err error, do func(error) bool) bool {
 			}
 		case interface{ Unwrap() []error }:
 			for _, e := range unw.Unwrap() {
-				if !walkRecur(e, do) {
+				if !walkRecur(e, do, path) {
 					return false
 				}
 			}
@@ -233,7 +1497,20 @@ func walkRecur(err error, do func(error) bool) bool {
 // [a, b, c, e, f]
 // [a, b, d, g]
 // [a, b, d, h]
+//
+// Each path starts with err and ends with a leaf, an error which doesn't
+// unwrap to any other errors. Errors with an Unwrap() error method extend
+// the current path, so a chain without any joined errors results in a single
+// path. Errors with an Unwrap() []error method start a new path for each of
+// the joined errors, in the order they are returned by Unwrap.
+// The paths are ordered depth first, i.e. all paths through the first joined
+// error are returned before any paths through the second.
+//
+// Flatten returns nil if err is nil.
 func Flatten(err error) [][]error {
+	if err == nil {
+		return nil
+	}
 	var ret [][]error
 	paths := [][]error{{err}}
 	for len(paths) > 0 {
@@ -255,21 +1532,28 @@ func extendPath(path []error) ([][]error, bool) {
 		return nil, false
 	}
 	last := path[len(path)-1]
+	// Errors already in the path would create a cycle, so end the path
+	cycle := func(nxt error) bool {
+		return len(path) >= internal.CycleCheckDepth && internal.OnPath(path, nxt)
+	}
 	switch unw := last.(type) {
 	case interface{ Unwrap() error }:
 		nxt := unw.Unwrap()
-		if nxt != nil {
+		if nxt != nil && !cycle(nxt) {
 			return [][]error{append(path, nxt)}, true
 		}
 	case interface{ Unwrap() []error }:
 		var ret [][]error
 		for _, nxt := range unw.Unwrap() {
+			if cycle(nxt) {
+				continue
+			}
 			p := make([]error, len(path), len(path)+1)
 			copy(p, path)
 			p = append(p, nxt)
 			ret = append(ret, p)
 		}
-		return ret, true
+		return ret, len(ret) > 0
 	}
 	return nil, false
 }
@@ -1,10 +1,16 @@
 package errors_test
 
 import (
+	"context"
 	stdlib_errors "errors"
+	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -13,6 +19,7 @@ import (
 	"github.com/peterlabuschagne/jettison/internal"
 	"github.com/peterlabuschagne/jettison/j"
 	"github.com/peterlabuschagne/jettison/models"
+	"github.com/peterlabuschagne/jettison/trace"
 )
 
 func TestNew(t *testing.T) {
@@ -54,6 +61,7 @@ func TestWrap(t *testing.T) {
 		opts []errors.Option
 
 		expectNil       bool
+		expectFolded    bool
 		expectedMessage string
 	}{
 		{
@@ -77,6 +85,7 @@ func TestWrap(t *testing.T) {
 			name:            "wrap empty message",
 			err:             errors.New("test value"),
 			msg:             "",
+			expectFolded:    true,
 			expectedMessage: "test value",
 		},
 		{
@@ -107,6 +116,7 @@ func TestWrap(t *testing.T) {
 			name:            "double empty wrapped message",
 			err:             errors.Wrap(errors.New("test value"), ""),
 			msg:             "",
+			expectFolded:    true,
 			expectedMessage: "test value",
 		},
 	}
@@ -122,6 +132,11 @@ func TestWrap(t *testing.T) {
 				return
 			}
 			je := err.(*internal.Error)
+			if tc.expectFolded {
+				assert.Equal(t, tc.expectedMessage, je.Message)
+				assert.Nil(t, je.Err)
+				return
+			}
 			assert.Equal(t, tc.msg, je.Message)
 			assert.Equal(t,
 				"errors_test.go TestWrap.func1",
@@ -261,11 +276,1078 @@ func TestGetCodes(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		tc := tc
-
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			codes := errors.GetCodes(tc.err)
+			assert.Equal(t, tc.expCodes, codes)
+		})
+	}
+}
+
+func TestIsErrorCode(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		target    errors.Code
+		expResult bool
+	}{
+		{name: "nil error", target: "code"},
+		{name: "stdlib error", err: io.EOF, target: "EOF"},
+		{name: "no code", err: errors.New("code"), target: "code"},
+		{
+			name:      "code",
+			err:       errors.New("test", j.C("code")),
+			target:    "code",
+			expResult: true,
+		},
+		{
+			name:   "different code",
+			err:    errors.New("test", j.C("code")),
+			target: "other",
+		},
+		{
+			name:      "wrapped",
+			err:       errors.Wrap(errors.New("test", j.C("inner")), "outer", j.C("outer")),
+			target:    "inner",
+			expResult: true,
+		},
+		{
+			name: "joined",
+			err: errors.Join(
+				errors.New("a", j.C("a")),
+				errors.New("b", j.C("b")),
+			),
+			target:    "b",
+			expResult: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expResult, errors.Is(tc.err, tc.target))
+			assert.Equal(t, tc.expResult, errors.IsAny(tc.err, io.ErrClosedPipe, tc.target))
+		})
+	}
+}
+
+func TestGetMessages(t *testing.T) {
+	testCases := []struct {
+		name        string
+		err         error
+		expMessages []string
+	}{
+		{name: "nil"},
+		{name: "non-jettison", err: io.EOF},
+		{
+			name:        "wrapped non-jettison",
+			err:         errors.Wrap(io.EOF, "read failed"),
+			expMessages: []string{"read failed"},
+		},
+		{
+			name: "chain",
+			err: errors.Wrap(
+				errors.Wrap(errors.New("c", j.C("ERR_C")), "b"),
+				"a",
+			),
+			expMessages: []string{"a", "b", "c"},
+		},
+		{
+			name:        "empty messages skipped",
+			err:         errors.Wrap(errors.Wrap(io.EOF, ""), "a"),
+			expMessages: []string{"a"},
+		},
+		{
+			name:        "joined",
+			err:         errors.Wrap(errors.Join(errors.New("b"), errors.New("c")), "a"),
+			expMessages: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expMessages, errors.GetMessages(tc.err))
+		})
+	}
+}
+
+func TestIsCode(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		code      string
+		expResult bool
+	}{
+		{name: "nil error", code: "code"},
+		{name: "stdlib error", err: io.EOF, code: "EOF"},
+		{
+			name:      "matching code",
+			err:       errors.New("test", errors.WithCode("code")),
+			code:      "code",
+			expResult: true,
+		},
+		{
+			name: "message is not a code",
+			err:  errors.New("test"),
+			code: "test",
+		},
+		{name: "empty code", err: errors.New("test")},
+		{
+			name: "wrapped empty code",
+			err:  errors.Wrap(errors.New("inner", errors.WithCode("inner")), "outer"),
+		},
+		{
+			name: "wrapped code",
+			err: errors.Wrap(errors.New("inner", errors.WithCode("inner")),
+				"outer", errors.WithCode("outer")),
+			code:      "inner",
+			expResult: true,
+		},
+		{
+			name: "joined code",
+			err: stdlib_errors.Join(
+				io.EOF,
+				errors.Wrap(errors.New("inner", errors.WithCode("inner")), "wrap"),
+			),
+			code:      "inner",
+			expResult: true,
+		},
+		{
+			name: "no match",
+			err:  errors.Wrap(errors.New("inner", errors.WithCode("inner")), "outer"),
+			code: "other",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expResult, errors.IsCode(tc.err, tc.code))
+		})
+	}
+}
+
+func TestHasCode(t *testing.T) {
+	err := errors.Wrap(errors.New("inner", errors.WithCode("inner")), "outer")
+
+	assert.True(t, errors.HasCode(err, "inner"))
+	assert.False(t, errors.HasCode(err, "outer"))
+	assert.False(t, errors.HasCode(err, ""))
+	assert.False(t, errors.HasCode(nil, "inner"))
+	assert.False(t, errors.HasCode(io.EOF, "EOF"))
+	assert.True(t, errors.HasCode(fmt.Errorf("stdlib: %w", err), "inner"))
+
+	// Joined errors aren't part of the chain
+	joined := errors.Join(errors.New("first"), err)
+	assert.True(t, errors.IsCode(joined, "inner"))
+	assert.False(t, errors.HasCode(joined, "inner"))
+}
+
+func TestNewf(t *testing.T) {
+	errors.SetTraceConfigTesting(t, errors.TestingConfig)
+
+	err := errors.Newf("failed after %d attempts: %s", 3, "timeout")
+	require.IsType(t, &internal.Error{}, err)
+	je := err.(*internal.Error)
+	assert.Equal(t, "failed after 3 attempts: timeout", je.Message)
+	assert.Equal(t, "errors_test.go TestNewf", je.Source)
+	assert.Equal(t, []string{"errors_test.go TestNewf"}, je.StackTrace)
+}
+
+func TestNewKV(t *testing.T) {
+	errors.SetTraceConfigTesting(t, errors.TestingConfig)
+
+	err := errors.NewKV("msg",
+		models.KeyValue{Key: "a", Value: "1"},
+		models.KeyValue{Key: "b", Value: "2"},
+	)
+	require.IsType(t, &internal.Error{}, err)
+	je := err.(*internal.Error)
+	assert.Equal(t, "errors_test.go TestNewKV", je.Source)
+	assert.Equal(t, []string{"errors_test.go TestNewKV"}, je.StackTrace)
+
+	exp := errors.New("msg", j.MKV{"a": 1, "b": "2"})
+	assert.True(t, errors.Equal(exp, err))
+	assert.Equal(t, exp.(*internal.Error).KV, je.KV)
+}
+
+type opError struct{ op string }
+
+func (e *opError) Error() string { return e.op + " failed" }
+
+type billingContext struct {
+	AccountID string
+}
+
+func TestWithMetadata(t *testing.T) {
+	err := errors.New("declined", errors.WithMetadata(billingContext{AccountID: "1"}))
+	err = errors.Wrap(err, "charge")
+	err = errors.Wrap(fmt.Errorf("fmt: %w", err), "checkout", errors.WithMetadata(42))
+	err = errors.Wrap(err, "")
+
+	var bc billingContext
+	require.True(t, errors.As(err, &bc))
+	assert.Equal(t, billingContext{AccountID: "1"}, bc)
+
+	var i int
+	require.True(t, errors.As(err, &i))
+	assert.Equal(t, 42, i)
+
+	var s string
+	assert.False(t, errors.As(err, &s))
+
+	// The outermost value wins
+	err = errors.Wrap(err, "retry", errors.WithMetadata(billingContext{AccountID: "2"}))
+	require.True(t, errors.As(err, &bc))
+	assert.Equal(t, billingContext{AccountID: "2"}, bc)
+
+	var je *internal.Error
+	assert.True(t, errors.As(err, &je))
+}
+
+func TestWithMetadataNotShared(t *testing.T) {
+	base := errors.New("base", errors.WithMetadata(1))
+	a := errors.Wrap(base, "", errors.WithMetadata("a"))
+	b := errors.Wrap(base, "", errors.WithMetadata("b"))
+
+	var s string
+	require.True(t, errors.As(a, &s))
+	assert.Equal(t, "a", s)
+	require.True(t, errors.As(b, &s))
+	assert.Equal(t, "b", s)
+	assert.False(t, errors.As(base, &s))
+}
+
+func TestAnnotate(t *testing.T) {
+	base := errors.New("base", errors.WithKV("k", "base"))
+	err := errors.Annotate(base, models.KeyValue{Key: "k", Value: "new"}, models.KeyValue{Key: "other", Value: "v"})
+
+	assert.Equal(t, "base", err.Error())
+	assert.Equal(t, []models.KeyValue{
+		{Key: "k", Value: "new"},
+		{Key: "other", Value: "v"},
+		{Key: "k", Value: "base"},
+	}, errors.GetKeyValueList(err))
+	assert.Equal(t, "new", errors.GetKeyValues(err)["k"])
+	assert.True(t, errors.Is(err, base))
+	assert.Equal(t, []models.KeyValue{{Key: "k", Value: "base"}}, errors.GetKeyValueList(base))
+
+	t.Run("no trace or source added", func(t *testing.T) {
+		sentinel := errors.New("sentinel", errors.WithoutStackTrace(), errors.WithoutSource())
+		err := errors.Annotate(sentinel, models.KeyValue{Key: "k", Value: "v"})
+		_, _, found := errors.GetLastStackTrace(err)
+		assert.False(t, found)
+		assert.Empty(t, err.(*internal.Error).Source)
+	})
+
+	t.Run("non-jettison", func(t *testing.T) {
+		err := errors.Annotate(io.EOF, models.KeyValue{Key: "k", Value: "v"})
+		assert.Equal(t, io.EOF.Error(), err.Error())
+		assert.True(t, errors.Is(err, io.EOF))
+		assert.Equal(t, map[string]string{"k": "v"}, errors.GetKeyValues(err))
+		_, _, found := errors.GetLastStackTrace(err)
+		assert.False(t, found)
+	})
+
+	t.Run("nothing to add", func(t *testing.T) {
+		assert.Nil(t, errors.Annotate(nil, models.KeyValue{Key: "k", Value: "v"}))
+		assert.Equal(t, base, errors.Annotate(base))
+	})
+}
+
+func TestEqualUnordered(t *testing.T) {
+	testCases := []struct {
+		name string
+		a, b error
+		exp  bool
+	}{
+		{name: "nil", exp: true},
+		{name: "nil and error", a: errors.New("a")},
+		{
+			name: "same order",
+			a:    errors.Wrap(errors.New("a", errors.WithCode("A")), "b"),
+			b:    errors.Wrap(errors.New("a", errors.WithCode("A")), "b"),
+			exp:  true,
+		},
+		{
+			name: "different order",
+			a:    errors.Wrap(errors.New("a", errors.WithCode("A")), "b", errors.WithKV("k", "v")),
+			b:    errors.Wrap(errors.New("b", errors.WithKV("k", "v")), "a", errors.WithCode("A")),
+			exp:  true,
+		},
+		{
+			name: "key/value order ignored",
+			a:    errors.New("a", errors.WithKV("k1", "v1"), errors.WithKV("k2", "v2")),
+			b:    errors.New("a", errors.WithKV("k2", "v2"), errors.WithKV("k1", "v1")),
+			exp:  true,
+		},
+		{
+			name: "joined in different order",
+			a:    errors.Join(errors.New("a"), errors.Wrap(io.EOF, "b")),
+			b:    errors.Join(errors.New("b"), errors.Wrap(io.EOF, "a")),
+			exp:  true,
+		},
+		{
+			name: "different key/value",
+			a:    errors.New("a", errors.WithKV("k", "v1")),
+			b:    errors.New("a", errors.WithKV("k", "v2")),
+		},
+		{
+			name: "different code",
+			a:    errors.New("a", errors.WithCode("A")),
+			b:    errors.New("a", errors.WithCode("B")),
+		},
+		{
+			name: "different number of errors",
+			a:    errors.Wrap(errors.New("a"), "a"),
+			b:    errors.New("a"),
+		},
+		{
+			name: "non-jettison by message",
+			a:    errors.Wrap(io.EOF, "a"),
+			b:    errors.Wrap(stdlib_errors.New("EOF"), "a"),
+			exp:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exp, errors.EqualUnordered(tc.a, tc.b))
+			assert.Equal(t, tc.exp, errors.EqualUnordered(tc.b, tc.a))
+		})
+	}
+}
+
+func TestGetBinaries(t *testing.T) {
+	hop := func(bin string, err error) error {
+		return &internal.Error{Message: bin, Binary: bin, Err: err}
+	}
+	testCases := []struct {
+		name string
+		err  error
+		exp  []string
+	}{
+		{name: "nil"},
+		{name: "non-jettison", err: io.EOF},
+		{name: "no trace", err: errors.New("a", errors.WithoutStackTrace())},
+		{
+			name: "origin first",
+			err:  hop("gateway", errors.Wrap(hop("orders", hop("payments", nil)), "wrap")),
+			exp:  []string{"payments", "orders", "gateway"},
+		},
+		{
+			name: "distinct",
+			err:  hop("gateway", hop("orders", hop("gateway", hop("payments", nil)))),
+			exp:  []string{"payments", "gateway", "orders"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exp, errors.GetBinaries(tc.err))
+		})
+	}
+}
+
+func TestUnwrapAll(t *testing.T) {
+	inner := errors.New("a")
+	fmtErr := fmt.Errorf("fmt: %w", inner)
+	wrapped := errors.Wrap(fmtErr, "b")
+	join := errors.Join(wrapped, io.EOF)
+	outer := errors.Wrap(join, "c")
+
+	testCases := []struct {
+		name string
+		err  error
+		exp  []error
+	}{
+		{name: "nil"},
+		{name: "single", err: io.EOF, exp: []error{io.EOF}},
+		{name: "wrapped", err: wrapped, exp: []error{wrapped, fmtErr, inner}},
+		{
+			name: "joined uses first error",
+			err:  outer,
+			exp: []error{
+				outer,
+				join,
+				stdlib_errors.Unwrap(join),
+				wrapped, fmtErr, inner,
+			},
+		},
+		{name: "empty join", err: errors.Join(), exp: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exp, errors.UnwrapAll(tc.err))
+		})
+	}
+}
+
+func TestMap(t *testing.T) {
+	errNotFound := errors.New("not found", errors.C("not_found"))
+	toNotFound := func(err error) error {
+		if err == io.EOF {
+			return errNotFound
+		}
+		return nil
+	}
+
+	t.Run("replaces wrapped error", func(t *testing.T) {
+		err := errors.Wrap(io.EOF, "read user", j.KV("id", 1))
+		act := errors.Map(err, toNotFound)
+
+		assert.Equal(t, "read user: not found", act.Error())
+		assert.True(t, errors.Is(act, errNotFound))
+		assert.False(t, errors.Is(act, io.EOF))
+		assert.Equal(t, []models.KeyValue{{Key: "id", Value: "1"}}, errors.GetKeyValueList(act))
+		// The original error is unchanged
+		assert.True(t, errors.Is(err, io.EOF))
+	})
+
+	t.Run("replaces through fmt wrapper", func(t *testing.T) {
+		err := errors.Wrap(fmt.Errorf("query: %w", io.EOF), "read user")
+		act := errors.Map(err, toNotFound)
+
+		assert.Equal(t, "read user: query: not found", act.Error())
+		assert.True(t, errors.Is(act, errNotFound))
+	})
+
+	t.Run("replaces joined errors", func(t *testing.T) {
+		other := errors.New("other")
+		err := errors.Join(io.EOF, other)
+		act := errors.Map(err, toNotFound)
+
+		assert.True(t, errors.Is(act, errNotFound))
+		assert.True(t, errors.Is(act, other))
+		assert.False(t, errors.Is(act, io.EOF))
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		err := errors.Wrap(errors.New("a"), "b")
+		assert.Equal(t, err, errors.Map(err, toNotFound))
+		assert.Nil(t, errors.Map(nil, toNotFound))
+	})
+}
+
+func TestWrapWith(t *testing.T) {
+	t.Run("codes at both levels", func(t *testing.T) {
+		root := errors.New("root")
+		err := errors.Wrap(root, "middle", errors.C("middle"))
+		act := errors.WrapWith(err, "outer",
+			[]errors.Option{errors.C("root_code"), j.KV("id", 1)},
+			errors.C("outer_code"))
+
+		assert.Equal(t, "outer: middle: root", act.Error())
+		assert.Equal(t, []string{"outer_code", "middle", "root_code"}, errors.GetCodes(act))
+		assert.Equal(t, []models.KeyValue{{Key: "id", Value: "1"}}, errors.GetKeyValueList(act))
+		assert.Contains(t, act.(*internal.Error).Source, "errors_test.go")
+		// The original errors are unchanged
+		assert.Equal(t, []string{"middle", "root"}, errors.GetCodes(err))
+	})
+
+	t.Run("non-jettison error", func(t *testing.T) {
+		act := errors.WrapWith(io.EOF, "read",
+			[]errors.Option{errors.C("inner"), j.KV("inner", 1)},
+			errors.C("outer"), j.KV("outer", 2))
+
+		assert.True(t, errors.Is(act, io.EOF))
+		assert.Equal(t, []string{"outer"}, errors.GetCodes(act))
+		assert.Equal(t, []models.KeyValue{{Key: "inner", Value: "1"}, {Key: "outer", Value: "2"}},
+			errors.GetKeyValueList(act))
+		assert.Contains(t, act.(*internal.Error).Source, "errors_test.go")
+	})
+
+	t.Run("jettison wrapping non-jettison", func(t *testing.T) {
+		err := errors.Wrap(fmt.Errorf("query: %w", io.EOF), "get user")
+		act := errors.WrapWith(err, "handle", []errors.Option{errors.C("db")})
+
+		assert.Equal(t, []string{"handle", "db"}, errors.GetCodes(act))
+		assert.True(t, errors.Is(act, io.EOF))
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		assert.Nil(t, errors.WrapWith(nil, "msg", []errors.Option{errors.C("a")}))
+	})
+}
+
+func TestReplaceCode(t *testing.T) {
+	newChain := func() error {
+		err := errors.New("timeout", errors.C("db_timeout"))
+		err = errors.Wrap(err, "query", errors.C("internal"), j.KV("table", "users"))
+		return errors.Wrap(err, "get user")
+	}
+
+	t.Run("middle of chain", func(t *testing.T) {
+		err := newChain()
+		act := errors.ReplaceCode(err, "internal", "unavailable")
+
+		assert.Equal(t, "get user: query: timeout", act.Error())
+		assert.Equal(t, []string{"unavailable", "db_timeout"}, codes(act))
+		assert.Equal(t, []models.KeyValue{{Key: "table", Value: "users"}}, errors.GetKeyValueList(act))
+		assert.False(t, errors.IsCode(act, "internal"))
+		// The original error is unchanged
+		assert.Equal(t, []string{"internal", "db_timeout"}, codes(err))
+	})
+
+	t.Run("all or first", func(t *testing.T) {
+		err := errors.Wrap(newChain(), "retry", errors.C("db_timeout"))
+
+		all := errors.ReplaceCode(err, "db_timeout", "unavailable")
+		assert.Equal(t, []string{"unavailable", "internal", "unavailable"}, codes(all))
+
+		first := errors.ReplaceFirstCode(err, "db_timeout", "unavailable")
+		assert.Equal(t, []string{"unavailable", "internal", "db_timeout"}, codes(first))
+	})
+
+	t.Run("joined", func(t *testing.T) {
+		err := errors.Join(newChain(), errors.New("other", errors.C("internal")))
+
+		act := errors.ReplaceCode(err, "internal", "unavailable")
+		assert.Equal(t, []string{"unavailable", "db_timeout", "unavailable"}, codes(act))
+
+		act = errors.ReplaceFirstCode(err, "internal", "unavailable")
+		assert.Equal(t, []string{"unavailable", "db_timeout", "internal"}, codes(act))
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		err := newChain()
+		assert.Equal(t, err, errors.ReplaceCode(err, "missing", "unavailable"))
+		assert.Equal(t, err, errors.ReplaceFirstCode(err, "missing", "unavailable"))
+		assert.Nil(t, errors.ReplaceCode(nil, "internal", "unavailable"))
+	})
+}
+
+func TestFilter(t *testing.T) {
+	newErr := func() error {
+		err := errors.New("root", j.KV("_internal_query", "select"), j.KV("id", 1))
+		err = fmt.Errorf("fmt: %w", err)
+		err = errors.Join(err, errors.New("other", j.KV("_internal_host", "db1")))
+		return errors.Wrap(err, "outer", j.KV("user", "u"), j.KV("_internal", "x"))
+	}
+
+	t.Run("keys", func(t *testing.T) {
+		err := newErr()
+		act := errors.Filter(err, errors.FilterKeys("_internal"))
+
+		assert.Equal(t, err.Error(), act.Error())
+		assert.Equal(t, map[string]string{"id": "1", "user": "u"}, errors.GetKeyValues(act))
+		assert.Len(t, errors.Flatten(act), len(errors.Flatten(err)))
+		for _, path := range errors.Flatten(act) {
+			for _, e := range path {
+				if je, ok := e.(*internal.Error); ok {
+					for _, kv := range je.KV {
+						assert.NotContains(t, kv.Key, "_internal")
+					}
+				}
+			}
+		}
+		// The original error is unchanged
+		assert.Equal(t, "select", errors.GetKeyValues(err)["_internal_query"])
+		assert.Equal(t, "db1", errors.GetKeyValues(err)["_internal_host"])
+		assert.Equal(t, "x", errors.GetKeyValues(err)["_internal"])
+	})
+
+	t.Run("stack traces", func(t *testing.T) {
+		err := newErr()
+		act := errors.Filter(err, errors.FilterStackTraces())
+
+		_, _, found := errors.GetLastStackTrace(act)
+		assert.False(t, found)
+		assert.Empty(t, errors.GetBinaries(act))
+		assert.Equal(t, errors.GetKeyValues(err), errors.GetKeyValues(act))
+		_, _, found = errors.GetLastStackTrace(err)
+		assert.True(t, found)
+	})
+
+	t.Run("no options", func(t *testing.T) {
+		err := newErr()
+		assert.Equal(t, err, errors.Filter(err))
+		assert.Nil(t, errors.Filter(nil, errors.FilterStackTraces()))
+		assert.Equal(t, io.EOF, errors.Filter(io.EOF, errors.FilterStackTraces()))
+	})
+}
+
+// codes returns the codes of the jettison errors in err, in the order
+// visited by Walk.
+func codes(err error) []string {
+	var ret []string
+	errors.Walk(err, func(err error) bool {
+		if je, ok := err.(*internal.Error); ok && je.Code != "" {
+			ret = append(ret, je.Code)
+		}
+		return true
+	})
+	return ret
+}
+
+func TestCause(t *testing.T) {
+	opErr := &opError{op: "dial"}
+	testCases := []struct {
+		name     string
+		err      error
+		expCause error
+	}{
+		{name: "nil"},
+		{name: "jettison only", err: errors.Wrap(errors.New("a"), "b")},
+		{name: "non-jettison", err: io.EOF, expCause: io.EOF},
+		{
+			name:     "wrapped",
+			err:      errors.Wrap(errors.Wrap(opErr, "a"), "b"),
+			expCause: opErr,
+		},
+		{
+			name:     "wrapped by fmt",
+			err:      errors.Wrap(fmt.Errorf("fmt: %w", errors.Wrap(opErr, "a")), "b"),
+			expCause: opErr,
+		},
+		{
+			name:     "joined uses first error",
+			err:      errors.Wrap(errors.Join(errors.Wrap(opErr, "a"), io.EOF), "b"),
+			expCause: opErr,
+		},
+		{
+			name: "joined first error has no cause",
+			err:  errors.Join(errors.New("a"), io.EOF),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expCause, errors.Cause(tc.err))
+		})
+	}
+
+	t.Run("fmt wrapping jettison error", func(t *testing.T) {
+		fmtErr := fmt.Errorf("fmt: %w", errors.New("a"))
+		assert.Equal(t, fmtErr, errors.Cause(errors.Wrap(fmtErr, "b")))
+	})
+}
+
+func TestEqual(t *testing.T) {
+	newErr := func() error {
+		return errors.Wrap(
+			errors.New("inner", j.C("ERR_1"), j.KV("k", "v")),
+			"outer",
+		)
+	}
+	testCases := []struct {
+		name     string
+		a, b     error
+		expEqual bool
+	}{
+		{name: "nil", expEqual: true},
+		{name: "nil and error", a: io.EOF},
+		{name: "same error", a: io.EOF, b: io.EOF, expEqual: true},
+		{
+			name:     "same type and message",
+			a:        stdlib_errors.New("hello"),
+			b:        stdlib_errors.New("hello"),
+			expEqual: true,
+		},
+		{
+			name: "different type",
+			a:    stdlib_errors.New("EOF"),
+			b:    fmt.Errorf("%w", io.EOF),
+		},
+		{
+			name:     "ignores stack traces and sources",
+			a:        newErr(),
+			b:        newErr(),
+			expEqual: true,
+		},
+		{
+			name: "different message",
+			a:    errors.New("a"),
+			b:    errors.New("b"),
+		},
+		{
+			name: "different code",
+			a:    errors.New("a", j.C("one")),
+			b:    errors.New("a", j.C("two")),
+		},
+		{
+			name: "different kvs",
+			a:    errors.New("a", j.KV("k", "1")),
+			b:    errors.New("a", j.KV("k", "2")),
+		},
+		{
+			name: "jettison and non-jettison",
+			a:    errors.Wrap(io.EOF, "a"),
+			b:    fmt.Errorf("a: %w", io.EOF),
+		},
+		{
+			name: "different wrapped error",
+			a:    errors.Wrap(io.EOF, "a"),
+			b:    errors.Wrap(io.ErrUnexpectedEOF, "a"),
+		},
+		{
+			name:     "joined",
+			a:        errors.Join(errors.New("a"), io.EOF),
+			b:        errors.Join(errors.New("a"), io.EOF),
+			expEqual: true,
+		},
+		{
+			name: "joined in different order",
+			a:    errors.Join(errors.New("a"), errors.New("b")),
+			b:    errors.Join(errors.New("b"), errors.New("a")),
+		},
+		{
+			name:     "wrapped by fmt",
+			a:        fmt.Errorf("wrap: %w", errors.New("a", j.C("c"))),
+			b:        fmt.Errorf("wrap: %w", errors.New("a", j.C("c"))),
+			expEqual: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expEqual, errors.Equal(tc.a, tc.b))
+			assert.Equal(t, tc.expEqual, errors.Equal(tc.b, tc.a))
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		expResult bool
+	}{
+		{name: "nil error"},
+		{name: "stdlib error", err: io.EOF},
+		{name: "not retryable", err: errors.New("test")},
+		{
+			name:      "retryable",
+			err:       errors.New("test", errors.WithRetryable()),
+			expResult: true,
+		},
+		{
+			name:      "wrapped retryable",
+			err:       errors.Wrap(errors.New("inner", errors.WithRetryable()), "outer"),
+			expResult: true,
+		},
+		{
+			name:      "wrapped with retryable",
+			err:       errors.Wrap(io.EOF, "outer", errors.WithRetryable()),
+			expResult: true,
+		},
+		{
+			name: "joined retryable",
+			err: stdlib_errors.Join(
+				errors.New("one"),
+				errors.New("two", errors.WithRetryable()),
+			),
+			expResult: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expResult, errors.IsRetryable(tc.err))
+
+			wrapped := errors.Wrap(tc.err, "wrap")
+			if wrapped == nil {
+				return
+			}
+			temp, ok := wrapped.(interface{ Temporary() bool })
+			require.True(t, ok)
+			assert.Equal(t, tc.expResult, temp.Temporary())
+		})
+	}
+}
+
+func TestIsTimeout(t *testing.T) {
+	errors.RegisterTimeoutCode("test_timeout")
+
+	testCases := []struct {
+		name      string
+		err       error
+		expResult bool
+	}{
+		{name: "nil error"},
+		{name: "stdlib error", err: io.EOF},
+		{name: "canceled", err: context.Canceled},
+		{name: "not a timeout", err: errors.New("test", errors.WithCode("test_other"))},
+		{
+			name:      "deadline exceeded",
+			err:       context.DeadlineExceeded,
+			expResult: true,
+		},
+		{
+			name:      "wrapped deadline exceeded",
+			err:       errors.Wrap(context.DeadlineExceeded, "wrap"),
+			expResult: true,
+		},
+		{
+			name:      "os deadline exceeded",
+			err:       fmt.Errorf("read: %w", os.ErrDeadlineExceeded),
+			expResult: true,
+		},
+		{
+			name:      "timeout code",
+			err:       errors.New("test", errors.WithCode("test_timeout")),
+			expResult: true,
+		},
+		{
+			name:      "wrapped timeout code",
+			err:       errors.Wrap(errors.New("test", errors.WithCode("test_timeout")), "wrap", errors.WithCode("test_other")),
+			expResult: true,
+		},
+		{
+			name:      "joined timeout",
+			err:       stdlib_errors.Join(io.EOF, errors.Wrap(context.DeadlineExceeded, "wrap")),
+			expResult: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expResult, errors.IsTimeout(tc.err))
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	testCases := []struct {
+		name    string
+		err     error
+		expCode int
+		expOK   bool
+		expOr   int
+	}{
+		{name: "nil error"},
+		{name: "stdlib error", err: io.EOF, expOr: 1},
+		{name: "no exit code", err: errors.New("test"), expOr: 1},
+		{
+			name:    "exit code",
+			err:     errors.New("test", errors.WithExitCode(2)),
+			expCode: 2,
+			expOK:   true,
+			expOr:   2,
+		},
+		{
+			name:    "latest wrapped exit code wins",
+			err:     errors.Wrap(errors.New("inner", errors.WithExitCode(2)), "outer", errors.WithExitCode(3)),
+			expCode: 3,
+			expOK:   true,
+			expOr:   3,
+		},
+		{
+			name:    "joined",
+			err:     errors.Join(io.EOF, errors.New("inner", errors.WithExitCode(2))),
+			expCode: 2,
+			expOK:   true,
+			expOr:   2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, ok := errors.ExitCode(tc.err)
+			assert.Equal(t, tc.expCode, code)
+			assert.Equal(t, tc.expOK, ok)
+			assert.Equal(t, tc.expOr, errors.ExitCodeOr(tc.err, 1))
+		})
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		expStatus int
+		expOK     bool
+	}{
+		{name: "nil error"},
+		{name: "stdlib error", err: io.EOF},
+		{name: "no status", err: errors.New("test")},
+		{
+			name:      "status",
+			err:       errors.New("test", errors.WithHTTPStatus(http.StatusNotFound)),
+			expStatus: http.StatusNotFound,
+			expOK:     true,
+		},
+		{
+			name: "wrapped status",
+			err: errors.Wrap(
+				errors.New("inner", errors.WithHTTPStatus(http.StatusNotFound)),
+				"outer",
+			),
+			expStatus: http.StatusNotFound,
+			expOK:     true,
+		},
+		{
+			name: "latest wrapped status wins",
+			err: errors.Wrap(
+				errors.New("inner", errors.WithHTTPStatus(http.StatusNotFound)),
+				"outer", errors.WithHTTPStatus(http.StatusBadRequest),
+			),
+			expStatus: http.StatusBadRequest,
+			expOK:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, ok := errors.HTTPStatus(tc.err)
+			assert.Equal(t, tc.expStatus, status)
+			assert.Equal(t, tc.expOK, ok)
+		})
+	}
+}
+
+func TestGetTags(t *testing.T) {
+	testCases := []struct {
+		name    string
+		err     error
+		expTags []string
+	}{
+		{name: "nil error"},
+		{name: "stdlib error", err: io.EOF},
+		{name: "no tags", err: errors.New("test", errors.WithCode("code"))},
+		{
+			name:    "tags",
+			err:     errors.New("test", errors.WithTags("external", "payment")),
+			expTags: []string{"external", "payment"},
+		},
+		{
+			name: "union without duplicates",
+			err: errors.Wrap(
+				errors.New("inner", errors.WithTags("external", "payment")),
+				"outer", errors.WithTags("transient", "external"),
+			),
+			expTags: []string{"transient", "external", "payment"},
+		},
+		{
+			name: "joined",
+			err: errors.Join(
+				errors.New("a", errors.WithTags("a")),
+				errors.New("b", errors.WithTags("b", "a")),
+			),
+			expTags: []string{"a", "b"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expTags, errors.GetTags(tc.err))
+		})
+	}
+}
+
+// tickingClock returns a clock which starts at start and advances by a second
+// each time it's called.
+func tickingClock(start time.Time) func() time.Time {
+	next := start
+	return func() time.Time {
+		now := next
+		next = next.Add(time.Second)
+		return now
+	}
+}
+
+func TestGetTimestamps(t *testing.T) {
+	start := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	errors.SetClockTesting(t, tickingClock(start))
+
+	inner := errors.New("inner")
+	err := errors.Wrap(fmt.Errorf("fmt: %w", inner), "middle")
+	err = errors.Wrap(err, "", errors.WithKV("k", "v"))
+	err = errors.Wrap(err, "outer")
+
+	assert.Equal(t, []time.Time{
+		start.Add(2 * time.Second),
+		start.Add(time.Second),
+		start,
+	}, errors.GetTimestamps(err))
+	assert.Nil(t, errors.GetTimestamps(io.EOF))
+}
+
+func TestSeverity(t *testing.T) {
+	testCases := []struct {
+		name        string
+		err         error
+		expSeverity string
+		expOK       bool
+	}{
+		{name: "nil error"},
+		{name: "stdlib error", err: io.EOF},
+		{name: "no severity", err: errors.New("test")},
+		{
+			name:        "wrapped severity",
+			err:         errors.Wrap(errors.New("inner", errors.WithSeverity("info")), "outer"),
+			expSeverity: "info",
+			expOK:       true,
+		},
+		{
+			name: "latest wrapped severity wins",
+			err: errors.Wrap(
+				errors.New("inner", errors.WithSeverity("info")),
+				"outer", errors.WithSeverity("warn"),
+			),
+			expSeverity: "warn",
+			expOK:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			severity, ok := errors.Severity(tc.err)
+			assert.Equal(t, tc.expSeverity, severity)
+			assert.Equal(t, tc.expOK, ok)
+		})
+	}
+}
+
+func TestGetKeyValues(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		expKVs    map[string]string
+		expKVList []models.KeyValue
+	}{
+		{
+			name:   "stdlib error returns nothing",
+			err:    stdlib_errors.New("test"),
+			expKVs: map[string]string{},
+		},
+		{
+			name:   "no key values",
+			err:    errors.New("test"),
+			expKVs: map[string]string{},
+		},
+		{
+			name: "wrapped error, most recent first",
+			err: errors.Wrap(
+				errors.New("inner", errors.WithKVs("key", "inner", "a", "1")),
+				"outer", errors.WithKV("key", "outer"),
+			),
+			expKVs: map[string]string{"key": "outer", "a": "1"},
+			expKVList: []models.KeyValue{
+				{Key: "key", Value: "outer"},
+				{Key: "key", Value: "inner"},
+				{Key: "a", Value: "1"},
+			},
+		},
+		{
+			name: "joined errors",
+			err: errors.Wrap(stdlib_errors.Join(
+				errors.New("one", errors.WithKV("one", "1")),
+				io.EOF,
+				errors.New("two", errors.WithKV("two", "2")),
+			), "joined"),
+			expKVs: map[string]string{"one": "1", "two": "2"},
+			expKVList: []models.KeyValue{
+				{Key: "one", Value: "1"},
+				{Key: "two", Value: "2"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			codes := errors.GetCodes(tc.err)
-			assert.Equal(t, tc.expCodes, codes)
+			assert.Equal(t, tc.expKVs, errors.GetKeyValues(tc.err))
+			assert.Equal(t, tc.expKVList, errors.GetKeyValueList(tc.err))
 		})
 	}
 }
@@ -352,6 +1434,61 @@ func TestAs(t *testing.T) {
 	assert.False(t, errors.As(je, &err1))
 }
 
+func TestAsJettisonError(t *testing.T) {
+	inner := errors.New("inner", errors.C("inner"))
+	outer := errors.Wrap(inner, "outer", errors.C("outer"))
+	testCases := []struct {
+		name    string
+		err     error
+		expCode string
+	}{
+		{name: "nil"},
+		{name: "non-jettison", err: io.EOF},
+		{name: "jettison", err: outer, expCode: "outer"},
+		{
+			name:    "wrapped by fmt",
+			err:     fmt.Errorf("fmt: %w", outer),
+			expCode: "outer",
+		},
+		{
+			name:    "jettison wrapping non-jettison",
+			err:     errors.Wrap(fmt.Errorf("fmt: %w", inner), "outer", errors.C("outer")),
+			expCode: "outer",
+		},
+		{
+			name:    "std join branch",
+			err:     stdlib_errors.Join(io.EOF, fmt.Errorf("fmt: %w", outer)),
+			expCode: "outer",
+		},
+		{
+			name:    "first std join branch",
+			err:     stdlib_errors.Join(inner, outer),
+			expCode: "inner",
+		},
+		{
+			name:    "wrapped join",
+			err:     fmt.Errorf("fmt: %w", errors.Join(io.EOF, outer)),
+			expCode: "",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var je *errors.JettisonError
+			ok := errors.As(tc.err, &je)
+			if tc.err == nil || tc.err == io.EOF {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tc.expCode, je.Code)
+
+			je = nil
+			require.True(t, stdlib_errors.As(tc.err, &je))
+			assert.Equal(t, tc.expCode, je.Code)
+		})
+	}
+}
+
 var errTest = errors.New("test error", errors.WithCode("ERR_59bed5816cb39f35"))
 
 func TestIsUnwrap(t *testing.T) {
@@ -377,6 +1514,38 @@ func TestWithoutStackTrace(t *testing.T) {
 	assert.NotEmpty(t, err.StackTrace)
 }
 
+func TestNewWithoutOptions(t *testing.T) {
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	errors.SetClockTesting(t, func() time.Time { return ts })
+	noop := errors.ErrorOption(func(*internal.Error) {})
+
+	for _, mode := range []errors.TraceMode{errors.TraceModeFull, errors.TraceModeCompact, errors.TraceModeNone} {
+		errors.SetTraceMode(mode)
+		// The errors are created on the same line to have the same source
+		fast, slow := errors.New("msg"), errors.New("msg", noop)
+		assert.Equal(t, slow, fast)
+		assert.NotEmpty(t, fast.(*internal.Error).Source)
+	}
+	errors.SetTraceMode(errors.TraceModeFull)
+
+	fast := errors.New("msg").(*internal.Error)
+	assert.NotEmpty(t, fast.StackTrace)
+	assert.Contains(t, fast.StackTrace[0], "TestNewWithoutOptions")
+	assert.Contains(t, fast.Source, "errors_test.go")
+}
+
+func TestNewSentinel(t *testing.T) {
+	errFoo := errors.NewSentinel("foo", errors.WithCode("foo")).(*internal.Error)
+	assert.Empty(t, errFoo.Binary)
+	assert.Empty(t, errFoo.StackTrace)
+	assert.Equal(t, "foo", errFoo.Code)
+
+	err := errors.Wrap(errFoo, "wrap adds stack trace").(*internal.Error)
+	assert.NotEmpty(t, err.Binary)
+	assert.NotEmpty(t, err.StackTrace)
+	assert.True(t, errors.Is(err, errFoo))
+}
+
 func TestErrorMetadata(t *testing.T) {
 	testCases := []struct {
 		name       string
@@ -450,6 +1619,242 @@ func TestErrorMetadata(t *testing.T) {
 	}
 }
 
+func TestWithKV(t *testing.T) {
+	testCases := []struct {
+		name  string
+		err   error
+		expKV []models.KeyValue
+	}{
+		{
+			name:  "new with kv",
+			err:   errors.New("one", errors.WithKV("key", "value")),
+			expKV: []models.KeyValue{{Key: "key", Value: "value"}},
+		},
+		{
+			name:  "wrap non-jettison with kv",
+			err:   errors.Wrap(io.EOF, "hi", errors.WithKV("key", "value")),
+			expKV: []models.KeyValue{{Key: "key", Value: "value"}},
+		},
+		{
+			name: "multiple kvs",
+			err:  errors.New("one", errors.WithKVs("a", "1", "b", "2")),
+			expKV: []models.KeyValue{
+				{Key: "a", Value: "1"},
+				{Key: "b", Value: "2"},
+			},
+		},
+		{
+			name:  "kvs with trailing key",
+			err:   errors.New("one", errors.WithKVs("a", "1", "b")),
+			expKV: []models.KeyValue{{Key: "a", Value: "1"}, {Key: "b"}},
+		},
+		{
+			name: "key values",
+			err: errors.New("one", errors.WithKeyValues(
+				models.KeyValue{Key: "a", Value: "1"},
+				models.KeyValue{Key: "b", Value: "2"},
+			)),
+			expKV: []models.KeyValue{
+				{Key: "a", Value: "1"},
+				{Key: "b", Value: "2"},
+			},
+		},
+		{
+			name: "key values compose with j.KV in order",
+			err: errors.Wrap(io.EOF, "hi",
+				j.KV("a", 1),
+				errors.WithKeyValues([]models.KeyValue{{Key: "b", Value: "2"}, {Key: "c", Value: "3"}}...),
+				j.KV("d", 4),
+			),
+			expKV: []models.KeyValue{
+				{Key: "a", Value: "1"},
+				{Key: "b", Value: "2"},
+				{Key: "c", Value: "3"},
+				{Key: "d", Value: "4"},
+			},
+		},
+		{
+			name: "composes with j.KV",
+			err:  errors.New("one", errors.WithKV("a", "1"), j.KV("b", 2)),
+			expKV: []models.KeyValue{
+				{Key: "a", Value: "1"},
+				{Key: "b", Value: "2"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			je, ok := tc.err.(*internal.Error)
+			require.True(t, ok)
+			assert.Equal(t, tc.expKV, je.KV)
+		})
+	}
+}
+
+func TestWrapEmptyMessage(t *testing.T) {
+	errors.SetTraceConfigTesting(t, errors.TestingConfig)
+
+	t.Run("applies options to the wrapped error", func(t *testing.T) {
+		base := errors.New("base", errors.WithKV("key", "base"))
+		err := errors.Wrap(base, "", errors.WithCode("code"), errors.WithKV("key", "wrap"))
+
+		je := err.(*internal.Error)
+		assert.Equal(t, "base", je.Message)
+		assert.Equal(t, "code", je.Code)
+		assert.Equal(t, []models.KeyValue{
+			{Key: "key", Value: "wrap"},
+			{Key: "key", Value: "base"},
+		}, je.KV)
+		assert.Equal(t, map[string]string{"key": "wrap"}, errors.GetKeyValues(err))
+
+		// The original error is not modified
+		baseJe := base.(*internal.Error)
+		assert.Empty(t, baseJe.Code)
+		assert.Equal(t, []models.KeyValue{{Key: "key", Value: "base"}}, baseJe.KV)
+
+		// The copy still matches the original, even without a code
+		assert.True(t, errors.Is(err, base))
+		noCode := errors.New("base")
+		assert.True(t, errors.Is(errors.Wrap(errors.Wrap(noCode, "", errors.WithKV("k", "v")), "", errors.WithCode("x")), noCode))
+	})
+
+	t.Run("sentinel gets a stack trace", func(t *testing.T) {
+		errSentinel := errors.New("sentinel", errors.WithoutStackTrace())
+		je := errors.Wrap(errSentinel, "").(*internal.Error)
+		assert.NotEmpty(t, je.Binary)
+		assert.Equal(t, "errors_test.go TestWrapEmptyMessage.func2", je.Source)
+		assert.Empty(t, errSentinel.(*internal.Error).Binary)
+
+		// Sentinels are wrapped, so they still match
+		assert.Equal(t, errSentinel, je.Err)
+		err := errors.Wrap(errSentinel, "", errors.WithCode("x"))
+		assert.True(t, errors.Is(err, errSentinel))
+		assert.True(t, errors.IsCode(err, "x"))
+	})
+
+	t.Run("no empty messages in flatten", func(t *testing.T) {
+		err := errors.Wrap(errors.Wrap(errors.New("base"), ""), "", errors.WithKV("k", "v"))
+		paths := errors.Flatten(err)
+		require.Len(t, paths, 1)
+		require.Len(t, paths[0], 1)
+		assert.Equal(t, "base", paths[0][0].(*internal.Error).Message)
+	})
+
+	t.Run("non-jettison errors are wrapped", func(t *testing.T) {
+		je := errors.Wrap(io.EOF, "", errors.WithKV("k", "v")).(*internal.Error)
+		assert.Empty(t, je.Message)
+		assert.Equal(t, io.EOF, je.Err)
+		assert.NotEmpty(t, je.Binary)
+	})
+}
+
+func TestWrapSentinelConcurrent(t *testing.T) {
+	errSentinel := errors.New("sentinel", errors.WithoutStackTrace(),
+		errors.WithKV("key", "sentinel"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val := strconv.Itoa(i)
+
+			err := errors.Wrap(errSentinel, "", errors.WithKV("key", val))
+			assert.Equal(t, []string{"sentinel"}, errors.GetCodes(err))
+			assert.Equal(t, map[string]string{"key": val}, errors.GetKeyValues(err))
+			assert.True(t, errors.Is(err, errSentinel))
+
+			err = errors.Wrap(errSentinel, "wrap", errors.WithCode(val), errors.WithKV("key", val))
+			assert.Equal(t, []string{val, "sentinel"}, errors.GetCodes(err))
+			assert.Equal(t, map[string]string{"key": val}, errors.GetKeyValues(err))
+			assert.True(t, errors.Is(err, errSentinel))
+		}()
+	}
+	wg.Wait()
+
+	je := errSentinel.(*internal.Error)
+	assert.Empty(t, je.Code)
+	assert.Empty(t, je.StackTrace)
+	assert.Equal(t, []models.KeyValue{{Key: "key", Value: "sentinel"}}, je.KV)
+}
+
+// TestCopyFidelity checks that errors copied by the functions which modify
+// existing errors never share slices which are modified, by changing copies of
+// the same error concurrently. Run with -race.
+func TestCopyFidelity(t *testing.T) {
+	// Appending three key/values leaves spare capacity in the slice
+	shared := errors.New("shared", errors.C("shared"), errors.WithTags("a", "b", "c"),
+		j.KV("k1", 1), j.KV("k2", 2), j.KV("k3", 3))
+	orig := shared.(*internal.Error).Clone()
+	require.Greater(t, cap(orig.KV), 0)
+	require.Greater(t, cap(shared.(*internal.Error).KV), len(orig.KV))
+
+	copiers := map[string]func(err error, kv models.KeyValue) error{
+		"wrap without message": func(err error, kv models.KeyValue) error {
+			return errors.Wrap(err, "", errors.WithKeyValues(kv), errors.WithTags(kv.Value))
+		},
+		"annotate": func(err error, kv models.KeyValue) error {
+			return errors.Annotate(err, kv)
+		},
+		"wrap with": func(err error, kv models.KeyValue) error {
+			return errors.WrapWith(err, "wrap",
+				[]errors.Option{errors.WithKeyValues(kv), errors.WithTags(kv.Value)})
+		},
+		"replace code": func(err error, kv models.KeyValue) error {
+			err = errors.ReplaceCode(err, "shared", kv.Value)
+			return errors.Annotate(err, kv)
+		},
+	}
+	for name, copier := range copiers {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				kv := models.KeyValue{Key: "k4", Value: strconv.Itoa(i)}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := copier(shared, kv)
+					kvs := errors.GetKeyValueList(err)
+					assert.Contains(t, kvs, kv)
+					assert.Len(t, kvs, 4)
+				}()
+			}
+			wg.Wait()
+			assert.Equal(t, orig, shared.(*internal.Error).Clone())
+		})
+	}
+}
+
+func BenchmarkWrap(b *testing.B) {
+	base := errors.New("base", errors.WithKV("key", "value"))
+	errSentinel := errors.New("sentinel", errors.WithoutStackTrace())
+
+	benchmarks := []struct {
+		name string
+		err  error
+		msg  string
+		opts []errors.Option
+	}{
+		{name: "message", err: base, msg: "wrap"},
+		{name: "message with options", err: base, msg: "wrap", opts: []errors.Option{errors.WithKV("k", "v")}},
+		{name: "message without source", err: base, msg: "wrap", opts: []errors.Option{errors.WithoutSource()}},
+		{name: "empty message", err: base},
+		{name: "empty message with options", err: base, opts: []errors.Option{errors.WithKV("k", "v")}},
+		{name: "sentinel", err: errSentinel, msg: "wrap"},
+		{name: "non-jettison", err: io.EOF, msg: "wrap"},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = errors.Wrap(bm.err, bm.msg, bm.opts...)
+			}
+		})
+	}
+}
+
 func TestWithStacktrace(t *testing.T) {
 	base := errors.New("base").(*internal.Error)
 	assert.NotEmpty(t, base.StackTrace)
@@ -561,6 +1966,259 @@ func TestFlatten(t *testing.T) {
 	assert.Equal(t, exp, msgs)
 }
 
+type emptyJoin struct{}
+
+func (emptyJoin) Error() string   { return "empty join" }
+func (emptyJoin) Unwrap() []error { return nil }
+
+func TestFlattenOrdering(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expPaths [][]string
+	}{
+		{name: "nil"},
+		{
+			name:     "single error",
+			err:      io.EOF,
+			expPaths: [][]string{{"EOF"}},
+		},
+		{
+			name:     "linear chain",
+			err:      errors.Wrap(errors.Wrap(io.EOF, "b"), "a"),
+			expPaths: [][]string{{"a", "b", "EOF"}},
+		},
+		{
+			name:     "empty join is a leaf",
+			err:      errors.Wrap(emptyJoin{}, "a"),
+			expPaths: [][]string{{"a", "empty join"}},
+		},
+		{
+			name: "nested joins, depth first",
+			err: errors.Wrap(
+				stdlib_errors.Join(
+					errors.Wrap(
+						stdlib_errors.Join(
+							errors.New("c"),
+							errors.Wrap(errors.New("e"), "d"),
+						),
+						"b",
+					),
+					errors.New("f"),
+					errors.Wrap(
+						stdlib_errors.Join(errors.New("h"), errors.New("i")),
+						"g",
+					),
+				),
+				"a",
+			),
+			expPaths: [][]string{
+				{"a", "<join>", "b", "<join>", "c"},
+				{"a", "<join>", "b", "<join>", "d", "e"},
+				{"a", "<join>", "f"},
+				{"a", "<join>", "g", "<join>", "h"},
+				{"a", "<join>", "g", "<join>", "i"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var paths [][]string
+			for _, p := range errors.Flatten(tc.err) {
+				var names []string
+				for _, e := range p {
+					names = append(names, flattenName(e))
+				}
+				paths = append(paths, names)
+			}
+			assert.Equal(t, tc.expPaths, paths)
+		})
+	}
+}
+
+func flattenName(err error) string {
+	if je, ok := err.(*internal.Error); ok {
+		return je.Message
+	}
+	if unw, ok := err.(interface{ Unwrap() []error }); ok && len(unw.Unwrap()) > 0 {
+		return "<join>"
+	}
+	return err.Error()
+}
+
+func TestWalkOrder(t *testing.T) {
+	err := errors.Wrap(
+		stdlib_errors.Join(
+			errors.Wrap(
+				stdlib_errors.Join(
+					errors.New("c"),
+					errors.Wrap(errors.New("e"), "d"),
+				),
+				"b",
+			),
+			errors.New("f"),
+		),
+		"a",
+	)
+
+	var walked []error
+	errors.Walk(err, func(err error) bool {
+		walked = append(walked, err)
+		return true
+	})
+
+	var names []string
+	for _, e := range walked {
+		names = append(names, flattenName(e))
+	}
+	assert.Equal(t, []string{"a", "<join>", "b", "<join>", "c", "d", "e", "f"}, names)
+
+	// Walk visits errors in the same order as Flatten, without repeats
+	var flattened []error
+	seen := make(map[error]bool)
+	for _, p := range errors.Flatten(err) {
+		for _, e := range p {
+			if !seen[e] {
+				seen[e] = true
+				flattened = append(flattened, e)
+			}
+		}
+	}
+	assert.Equal(t, flattened, walked)
+}
+
+func TestJoin(t *testing.T) {
+	errors.SetTraceConfigTesting(t, errors.TestingConfig)
+
+	t.Run("nil errors", func(t *testing.T) {
+		assert.Nil(t, errors.Join())
+		assert.Nil(t, errors.Join(nil, nil))
+	})
+
+	errOne := errors.New("one", errors.WithCode("one"), errors.WithKV("k1", "v1"))
+	errTwo := errors.New("two", errors.WithCode("two"), errors.WithKV("k2", "v2"))
+
+	t.Run("skips nil errors", func(t *testing.T) {
+		err := errors.Join(errOne, nil, errTwo)
+		assert.Equal(t, "one\ntwo", err.Error())
+		assert.Len(t, errors.Flatten(err), 2)
+	})
+
+	t.Run("aggregates metadata", func(t *testing.T) {
+		err := errors.Join(errOne, errTwo)
+		assert.Equal(t, []string{"one", "two"}, errors.GetCodes(err))
+		assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2"}, errors.GetKeyValues(err))
+	})
+
+	t.Run("is and as descend", func(t *testing.T) {
+		err := errors.Join(io.EOF, errTwo)
+		assert.True(t, errors.Is(err, io.EOF))
+		assert.True(t, errors.Is(err, errTwo))
+
+		var te testErr
+		assert.True(t, errors.As(errors.Join(errOne, testErr("test")), &te))
+		assert.Equal(t, testErr("test"), te)
+	})
+
+	t.Run("stack trace", func(t *testing.T) {
+		je := errors.Join(errOne, errTwo).(*internal.Error)
+		assert.Empty(t, je.Binary)
+
+		je = errors.Join(errOne, io.EOF).(*internal.Error)
+		assert.NotEmpty(t, je.Binary)
+		assert.Equal(t, "errors_test.go TestJoin.func5", je.Source)
+	})
+}
+
+func TestWrapAll(t *testing.T) {
+	errors.SetTraceConfigTesting(t, errors.TestingConfig)
+
+	t.Run("nil errors", func(t *testing.T) {
+		assert.Nil(t, errors.WrapAll(nil, "wrap"))
+		assert.Nil(t, errors.WrapAll([]error{nil, nil}, "wrap"))
+	})
+
+	errOne := errors.New("one", errors.WithCode("one"))
+	errTwo := errors.New("two", errors.WithCode("two"))
+
+	t.Run("single error", func(t *testing.T) {
+		err := errors.WrapAll([]error{nil, io.EOF, nil}, "wrap", errors.WithCode("wrap"))
+		assert.Equal(t, "wrap: EOF", err.Error())
+		assert.Len(t, errors.Flatten(err), 1)
+		assert.True(t, errors.Is(err, io.EOF))
+		assert.Equal(t, []string{"wrap"}, errors.GetCodes(err))
+
+		je := err.(*internal.Error)
+		assert.Equal(t, "errors_test.go TestWrapAll.func2", je.Source)
+		assert.NotEmpty(t, je.Binary)
+	})
+
+	t.Run("multiple errors", func(t *testing.T) {
+		err := errors.WrapAll([]error{errOne, nil, io.EOF, errTwo}, "wrap", errors.WithKV("k", "v"))
+		assert.Equal(t, "wrap: one\nEOF\ntwo", err.Error())
+		require.Len(t, errors.Flatten(err), 3)
+		assert.True(t, errors.Is(err, errOne))
+		assert.True(t, errors.Is(err, io.EOF))
+		assert.True(t, errors.Is(err, errTwo))
+		assert.Equal(t, map[string]string{"k": "v"}, errors.GetKeyValues(err))
+
+		je := err.(*internal.Error)
+		assert.Equal(t, "wrap", je.Message)
+		assert.Equal(t, "errors_test.go TestWrapAll.func3", je.Source)
+		joined := je.Err.(*internal.Error)
+		assert.Equal(t, "errors_test.go TestWrapAll.func3", joined.Source)
+		assert.NotEmpty(t, joined.Binary)
+		assert.Empty(t, je.Binary)
+	})
+}
+
+func TestCombine(t *testing.T) {
+	base := errors.New("db down", errors.WithCode("db_down"))
+
+	t.Run("merges identical branches", func(t *testing.T) {
+		err := errors.Join(
+			errors.Wrap(base, "query", errors.WithKV("a", "1")),
+			errors.Wrap(base, "query", errors.WithKV("b", "2")),
+		)
+		require.Len(t, errors.Flatten(err), 2)
+
+		act := errors.Combine(err)
+		require.Len(t, errors.Flatten(act), 1)
+		assert.Equal(t, "query: db down", act.Error())
+		assert.Equal(t, []models.KeyValue{
+			{Key: "a", Value: "1"},
+			{Key: "b", Value: "2"},
+		}, errors.GetKeyValueList(act))
+		assert.True(t, errors.Is(act, base))
+		assert.True(t, errors.IsCode(act, "db_down"))
+
+		var traces int
+		errors.Walk(act, func(err error) bool {
+			if je, ok := err.(*internal.Error); ok && len(je.StackTrace) > 0 {
+				traces++
+			}
+			return true
+		})
+		assert.Equal(t, 1, traces)
+	})
+
+	t.Run("keeps different branches", func(t *testing.T) {
+		err := errors.Join(
+			errors.Wrap(base, "query"),
+			errors.Wrap(base, "update"),
+			errors.Wrap(errors.New("db down", errors.WithCode("other")), "query"),
+		)
+		assert.Equal(t, err, errors.Combine(err))
+	})
+
+	t.Run("no joins", func(t *testing.T) {
+		err := errors.Wrap(base, "query")
+		assert.Equal(t, err, errors.Combine(err))
+		assert.Nil(t, errors.Combine(nil))
+	})
+}
+
 func wrapStackTrace(err error) error {
 	return errors.Wrap(err, "", errors.WithStackTrace())
 }
@@ -611,3 +2269,186 @@ func TestGetLastStackTrace(t *testing.T) {
 		})
 	}
 }
+
+func wrapWithTrace(err error) error {
+	return errors.Wrap(err, "wrapped", errors.WithStackTrace())
+}
+
+func TestStackTrace(t *testing.T) {
+	errors.SetTraceConfigTesting(t, errors.TestingConfig)
+
+	origin := errors.New("origin")
+	wrapped := wrapWithTrace(origin)
+	_, last, _ := errors.GetLastStackTrace(wrapped)
+	require.Equal(t, []string{"errors_test.go wrapWithTrace", "errors_test.go TestStackTrace"}, last)
+	testCases := []struct {
+		name     string
+		err      error
+		expTrace []string
+	}{
+		{name: "nil"},
+		{name: "std error has no trace", err: io.EOF},
+		{
+			name:     "new error",
+			err:      origin,
+			expTrace: []string{"errors_test.go TestStackTrace"},
+		},
+		{
+			name:     "wrapped gets origin trace",
+			err:      wrapped,
+			expTrace: []string{"errors_test.go TestStackTrace"},
+		},
+		{
+			name:     "joined follows first error",
+			err:      errors.Join(wrapped, errors.New("other", errors.WithoutStackTrace())),
+			expTrace: []string{"errors_test.go TestStackTrace"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expTrace, errors.StackTrace(tc.err))
+		})
+	}
+}
+
+func TestCycle(t *testing.T) {
+	t.Cleanup(func() { errors.SetMaxHops(0) })
+	errors.SetMaxHops(5)
+
+	// Modifying an error after wrapping it is the only way to create a cycle
+	a := &internal.Error{Message: "a", Code: "a"}
+	b := errors.Wrap(a, "b", errors.WithCode("b"))
+	a.Err = stdlib_errors.Join(io.EOF, b)
+	c := &internal.Error{Message: "c"}
+	d := errors.Wrap(c, "d")
+	c.Err = d
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.Equal(t, "b", errors.GetCodes(b)[0])
+		assert.True(t, errors.IsCode(b, "a"))
+		assert.False(t, errors.IsCode(b, "c"))
+		assert.NotEmpty(t, errors.Flatten(b))
+		assert.NotEmpty(t, errors.UnwrapAll(b))
+		assert.NotNil(t, errors.Wrap(b, "c"))
+		assert.NotNil(t, errors.Wrap(d, "e"))
+		assert.NotEmpty(t, errors.Flatten(d))
+		assert.False(t, errors.HasCode(d, "x"))
+		assert.NotNil(t, trace.Frames(d))
+
+		for _, err := range []error{b, d} {
+			assert.NotEmpty(t, err.Error())
+			assert.NotEmpty(t, fmt.Sprintf("%#v", err))
+			assert.NotEmpty(t, fmt.Sprintf("%+v", err))
+			assert.True(t, errors.Equal(err, err))
+			assert.False(t, err.(interface{ Temporary() bool }).Temporary())
+		}
+		assert.False(t, errors.Equal(b, d))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("traversing a cycle didn't terminate")
+	}
+}
+
+func TestSharedErrors(t *testing.T) {
+	// Errors shared by many branches aren't cycles, however deep the tree is
+	sentinel := errors.New("sentinel", errors.WithCode("sentinel"), errors.WithRetryable(), errors.WithKV("k", "v"))
+	var errs []error
+	for i := 0; i < 80; i++ {
+		errs = append(errs, errors.Wrap(sentinel, "wrap"))
+	}
+	err := errors.Join(errs...)
+
+	assert.Len(t, errors.Flatten(err), 80)
+	var n int
+	errors.Walk(err, func(err error) bool {
+		if err == sentinel {
+			n++
+		}
+		return true
+	})
+	assert.Equal(t, 80, n)
+
+	var codes int
+	for _, c := range errors.GetCodes(err) {
+		if c == "sentinel" {
+			codes++
+		}
+	}
+	assert.Equal(t, 80, codes)
+
+	last := errors.Join(append(errs, errors.New("last", errors.WithCode("last")))...)
+	assert.True(t, errors.IsCode(last, "last"))
+	assert.True(t, errors.IsRetryable(err))
+	assert.Equal(t, map[string]string{"k": "v"}, errors.GetKeyValues(err))
+}
+
+func newQueryError(id int) error {
+	err := errors.New("not found", errors.WithCode("not_found"), errors.WithKV("id", strconv.Itoa(id)))
+	return errors.Wrap(err, "query failed")
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := errors.Fingerprint(newQueryError(1))
+	assert.Len(t, fp, 16)
+
+	// Different values and stack traces
+	assert.Equal(t, fp, errors.Fingerprint(newQueryError(2)))
+	assert.Equal(t, fp, errors.Fingerprint(errors.Wrap(
+		errors.New("not found", errors.WithCode("not_found"), errors.WithKV("id", "3")),
+		"query failed",
+	)))
+
+	testCases := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "different code",
+			err: errors.Wrap(errors.New("not found", errors.WithCode("other"), errors.WithKV("id", "1")),
+				"query failed"),
+		},
+		{
+			name: "different message",
+			err: errors.Wrap(errors.New("not found", errors.WithCode("not_found"), errors.WithKV("id", "1")),
+				"update failed"),
+		},
+		{
+			name: "different keys",
+			err: errors.Wrap(errors.New("not found", errors.WithCode("not_found"), errors.WithKV("name", "1")),
+				"query failed"),
+		},
+		{name: "extra hop", err: errors.Wrap(newQueryError(1), "outer")},
+		{name: "joined", err: errors.Join(newQueryError(1), io.EOF)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.NotEqual(t, fp, errors.Fingerprint(tc.err))
+		})
+	}
+
+	assert.Equal(t, errors.Fingerprint(io.EOF), errors.Fingerprint(io.EOF))
+	assert.NotEqual(t, errors.Fingerprint(io.EOF), errors.Fingerprint(io.ErrUnexpectedEOF))
+	assert.Empty(t, errors.Fingerprint(nil))
+}
+
+func BenchmarkNewNoOpts(b *testing.B) {
+	b.Run("no options", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = errors.New("msg")
+		}
+	})
+	b.Run("options", func(b *testing.B) {
+		opt := errors.WithSeverity("")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = errors.New("msg", opt)
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package errors
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/peterlabuschagne/jettison/internal"
+	"github.com/peterlabuschagne/jettison/models"
+)
+
+// CollapsedCodesKey is the key used to list the codes of errors collapsed by
+// SetMaxHops.
+const CollapsedCodesKey = "collapsed_codes"
+
+var maxHops atomic.Int32
+
+// SetMaxHops limits the number of JettisonErrors which can be chained
+// together by Wrap, n <= 0 means no limit which is the default.
+// Values of 1 and 2 are treated as 3.
+//
+// When Wrap exceeds the limit, the oldest errors between the top and bottom
+// of the chain are collapsed into a single error. The collapsed error has
+// the distinct messages, joined with ": ", the most recent code, the
+// distinct key/value pairs and tags, and all the metadata of the errors it
+// replaces. All their distinct codes are listed, comma separated, in the
+// CollapsedCodesKey key/value.
+// Only the oldest stack trace and timestamp are kept.
+//
+// Counting stops at the first non-jettison or joined error, which is kept
+// along with anything it wraps.
+func SetMaxHops(n int) {
+	maxHops.Store(int32(n))
+}
+
+// limitHops collapses the chain of JettisonErrors starting at je if it is
+// longer than the limit set by SetMaxHops.
+func limitHops(je *internal.Error) *internal.Error {
+	max := int(maxHops.Load())
+	if max <= 0 {
+		return je
+	}
+	if max < 3 {
+		max = 3
+	}
+
+	var hops []*internal.Error
+	for next := je; next != nil; {
+		if len(hops) >= internal.CycleCheckDepth && containsHop(hops, next) {
+			break
+		}
+		hops = append(hops, next)
+		next, _ = next.Err.(*internal.Error)
+	}
+	if len(hops) <= max {
+		return je
+	}
+
+	keep := hops[:max-2]
+	summary := collapse(hops[max-2 : len(hops)-1])
+	summary.Err = hops[len(hops)-1]
+
+	top := summary
+	for i := len(keep) - 1; i >= 0; i-- {
+		c := keep[i].Clone()
+		c.Err = top
+		top = c
+	}
+	return top
+}
+
+// collapse summarises a chain of errors into a single error, the errors
+// should be ordered most recent first.
+func collapse(hops []*internal.Error) *internal.Error {
+	var (
+		msgs  []string
+		codes []string
+		kvs   []models.KeyValue
+		ret   internal.Error
+	)
+	for _, h := range hops {
+		// Split messages so those from a previous collapse are deduplicated
+		for _, m := range strings.Split(h.Message, ": ") {
+			if m != "" && !contains(msgs, m) {
+				msgs = append(msgs, m)
+			}
+		}
+		// Codes listed by a previous collapse
+		for _, kv := range h.KV {
+			if kv.Key != CollapsedCodesKey {
+				continue
+			}
+			for _, c := range strings.Split(kv.Value, ",") {
+				if !contains(codes, c) {
+					codes = append(codes, c)
+				}
+			}
+		}
+		if h.Code != "" && !contains(codes, h.Code) {
+			codes = append(codes, h.Code)
+		}
+		if ret.Code == "" {
+			ret.Code = h.Code
+		}
+		for _, kv := range h.KV {
+			if kv.Key != CollapsedCodesKey && !containsKV(kvs, kv) {
+				kvs = append(kvs, kv)
+			}
+		}
+		if len(h.StackTrace) > 0 {
+			ret.Binary, ret.StackTrace = h.Binary, h.StackTrace
+		}
+		if h.Source != "" {
+			ret.Source = h.Source
+		}
+		if !h.Timestamp.IsZero() {
+			ret.Timestamp = h.Timestamp
+		}
+		ret.Retryable = ret.Retryable || h.Retryable
+		if ret.HTTPStatus == 0 {
+			ret.HTTPStatus = h.HTTPStatus
+		}
+		if ret.ExitCode == 0 {
+			ret.ExitCode = h.ExitCode
+		}
+		if ret.Severity == "" {
+			ret.Severity = h.Severity
+		}
+		// Keep the metadata of the most recent errors last, so As finds it
+		ret.Metadata = append(append([]any(nil), h.Metadata...), ret.Metadata...)
+		for _, t := range h.Tags {
+			if !contains(ret.Tags, t) {
+				ret.Tags = append(ret.Tags, t)
+			}
+		}
+	}
+	ret.Message = strings.Join(msgs, ": ")
+	ret.KV = kvs
+	if len(codes) > 0 {
+		ret.KV = append(ret.KV, models.KeyValue{
+			Key:   CollapsedCodesKey,
+			Value: strings.Join(codes, ","),
+		})
+	}
+	return &ret
+}
+
+func containsHop(hops []*internal.Error, je *internal.Error) bool {
+	for _, h := range hops {
+		if h == je {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(l []string, s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKV(l []models.KeyValue, kv models.KeyValue) bool {
+	for _, v := range l {
+		if v == kv {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package errors_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/peterlabuschagne/jettison/errors"
+	"github.com/peterlabuschagne/jettison/internal"
+	"github.com/peterlabuschagne/jettison/j"
+)
+
+func countHops(err error) int {
+	var n int
+	errors.Walk(err, func(err error) bool {
+		if _, ok := err.(*internal.Error); ok {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+func TestSetMaxHops(t *testing.T) {
+	t.Cleanup(func() { errors.SetMaxHops(0) })
+	errors.SetMaxHops(4)
+
+	bottom := errors.New("bottom", j.C("ERR_BOTTOM"))
+	err := errors.Wrap(bottom, "first", j.C("ERR_FIRST"), j.KV("attempt", "first"))
+	for i := 0; i < 100; i++ {
+		err = errors.Wrap(err, "retry", j.C(fmt.Sprint("ERR_", i%3)), j.KV("attempt", "n"))
+		require.LessOrEqual(t, countHops(err), 4)
+	}
+	err = errors.Wrap(err, "top", j.C("ERR_TOP"))
+
+	assert.Equal(t, 4, countHops(err))
+	assert.Equal(t, "top: retry: retry: first: bottom", err.Error())
+	assert.Equal(t, []string{"ERR_TOP", "ERR_0", "ERR_2", "ERR_BOTTOM"}, errors.GetCodes(err))
+	assert.True(t, errors.Is(err, bottom))
+	assert.Equal(t, map[string]string{
+		"attempt":         "n",
+		"collapsed_codes": "ERR_2,ERR_1,ERR_0,ERR_FIRST",
+	}, errors.GetKeyValues(err))
+}
+
+func TestSetMaxHopsNonJettison(t *testing.T) {
+	t.Cleanup(func() { errors.SetMaxHops(0) })
+	errors.SetMaxHops(1)
+
+	err := errors.Wrap(fmt.Errorf("fmt: %w", errors.New("inner")), "one")
+	for i := 0; i < 5; i++ {
+		err = errors.Wrap(err, "wrap")
+	}
+
+	assert.Equal(t, 4, countHops(err))
+	assert.Equal(t, "wrap: wrap: one: fmt: inner", err.Error())
+}
+
+func TestSetMaxHopsUnlimited(t *testing.T) {
+	err := errors.New("bottom")
+	for i := 0; i < 10; i++ {
+		err = errors.Wrap(err, "wrap")
+	}
+	assert.Equal(t, 11, countHops(err))
+}
@@ -0,0 +1,63 @@
+package errors
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/peterlabuschagne/jettison/internal"
+)
+
+var timeoutCodes = struct {
+	sync.RWMutex
+	codes map[string]bool
+}{codes: make(map[string]bool)}
+
+// RegisterTimeoutCode marks code as the code of timeout errors, so that
+// IsTimeout matches errors with it. Codes registered with
+// codes.DeadlineExceeded using the grpc package's RegisterCode are registered
+// automatically. It is typically called during init.
+func RegisterTimeoutCode(code string) {
+	timeoutCodes.Lock()
+	defer timeoutCodes.Unlock()
+	timeoutCodes.codes[code] = true
+}
+
+// IsTimeout returns true if err is a timeout, so that retry predicates don't
+// need to check for each kind of timeout separately. An error is a timeout if:
+//
+//   - Is(err, context.DeadlineExceeded), which includes errors received over
+//     gRPC with the codes.DeadlineExceeded status, see the grpc package.
+//   - Is(err, os.ErrDeadlineExceeded), which is returned by I/O deadlines,
+//     e.g. net.Conn.SetDeadline.
+//   - Any jettison error in the err error tree has a code registered with
+//     RegisterTimeoutCode, or with codes.DeadlineExceeded using the grpc
+//     package's RegisterCode. Unlike the others, this still matches once
+//     the error has been sent over gRPC by a server which doesn't use the
+//     codes.DeadlineExceeded status for it.
+//
+// For example:
+//
+//	if errors.IsTimeout(err) {
+//	  return retry(ctx)
+//	}
+func IsTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if Is(err, context.DeadlineExceeded) || Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	timeoutCodes.RLock()
+	defer timeoutCodes.RUnlock()
+	var found bool
+	Walk(err, func(err error) bool {
+		je, ok := err.(*internal.Error)
+		if ok && je.Code != "" && timeoutCodes.codes[je.Code] {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
+}
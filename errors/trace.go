@@ -2,6 +2,7 @@ package errors
 
 import (
 	"fmt"
+	"sync/atomic"
 	"testing"
 
 	"github.com/go-stack/stack"
@@ -41,15 +42,145 @@ var TestingConfig = trace.StackConfig{
 	},
 }
 
+// TraceMode sets how much of the stack trace is captured for errors.
+type TraceMode int32
+
+const (
+	// TraceModeFull captures the whole stack trace, which is the default.
+	TraceModeFull TraceMode = iota
+	// TraceModeCompact captures at most CompactTraceFrames frames,
+	// excluding the Go runtime and standard library.
+	TraceModeCompact
+	// TraceModeNone doesn't capture stack traces.
+	TraceModeNone
+)
+
+// CompactTraceFrames is the maximum number of frames captured with
+// TraceModeCompact.
+const CompactTraceFrames = 5
+
+var traceMode atomic.Int32
+
+// SetTraceMode sets how much of the stack trace is captured by subsequent
+// calls to New, Wrap and WithStackTrace, e.g. TraceModeCompact in production
+// or TraceModeNone in CI. It's safe to call concurrently with creating
+// errors.
+//
+// The frames included in the trace are still set by SetTraceConfig, and
+// per-error options are applied to the trace captured for the mode, i.e.
+// WithStackTraceDepth can limit it further and WithoutStackTrace removes it.
+func SetTraceMode(mode TraceMode) {
+	traceMode.Store(int32(mode))
+}
+
 // getTrace will get the current binary and a stacktrace
-// skip will omit a certain number of stack calls before getTrace
+// skip will omit a certain number of stack calls before getTrace,
+// i.e. a skip of 0 starts the trace at the function calling getTrace and
+// a skip of 1 at its caller. New and Wrap use 1 so that the trace starts
+// at the function which created the error.
 func getTrace(skip int) (string, []string) {
+	switch TraceMode(traceMode.Load()) {
+	case TraceModeNone:
+		return trace.CurrentBinary(), nil
+	case TraceModeCompact:
+		return trace.CurrentBinary(), compactTrace(trace.GetStackTrace(skip+1, compactConfig()))
+	}
 	// Skip GetStackTrace and getTrace
 	return trace.CurrentBinary(), trace.GetStackTrace(skip+1, traceConfig)
 }
 
-// getSourceCode will get the current
-// Skip getSourceCode
+// getTraceAt is like getTrace, but starts the trace at the program counter pc.
+// It returns false if pc isn't on the stack.
+func getTraceAt(pc uintptr) (string, []string, bool) {
+	switch TraceMode(traceMode.Load()) {
+	case TraceModeNone:
+		return trace.CurrentBinary(), nil, true
+	case TraceModeCompact:
+		_, tr, ok := trace.GetSourceAndStackTraceAt(pc, compactConfig())
+		return trace.CurrentBinary(), compactTrace(tr), ok
+	}
+	_, tr, ok := trace.GetSourceAndStackTraceAt(pc, traceConfig)
+	return trace.CurrentBinary(), tr, ok
+}
+
+// compactConfig returns the config for traces captured with TraceModeCompact.
+func compactConfig() trace.StackConfig {
+	cfg := traceConfig
+	cfg.TrimRuntime, cfg.TrimStdlib = true, true
+	return cfg
+}
+
+// compactTrace limits tr to CompactTraceFrames frames.
+func compactTrace(tr []string) []string {
+	if len(tr) > CompactTraceFrames {
+		return tr[:CompactTraceFrames]
+	}
+	return tr
+}
+
+// getCallerTrace returns the trace for an error created with the options ol,
+// starting at the program counter set by WrapAt if it's on the stack,
+// otherwise like getTrace, with the frames skipped by WithSkip added to skip.
+func getCallerTrace(skip int, ol []Option) (string, []string) {
+	if pc, ok := callerPC(ol); ok {
+		if bin, tr, ok := getTraceAt(pc); ok {
+			return bin, tr
+		}
+	}
+	return getTrace(skip + 1 + callerSkip(ol))
+}
+
+// getSourceAndTrace returns the same as getSourceCode and getTrace, but only
+// collects the calls once when the whole trace is captured.
+func getSourceAndTrace(skip int) (string, string, []string) {
+	if TraceMode(traceMode.Load()) != TraceModeFull {
+		bin, tr := getTrace(skip + 1)
+		return getSourceCode(skip + 1), bin, tr
+	}
+	src, tr := trace.GetSourceAndStackTrace(skip+1, traceConfig)
+	return src, trace.CurrentBinary(), tr
+}
+
+// getSourceCode will get the source code reference of a caller,
+// skip follows the same semantics as getTrace.
 func getSourceCode(skip int) string {
 	return trace.GetSourceCodeRef(skip+1, traceConfig)
 }
+
+// getSource returns the source code reference like getSourceCode, unless
+// ol contains WithoutSource. Frames skipped with WithSkip are added to skip,
+// and the program counter set by WrapAt is used instead if it's on the stack.
+func getSource(skip int, ol []Option) string {
+	for _, o := range ol {
+		if _, ok := o.(withoutSource); ok {
+			return ""
+		}
+	}
+	if pc, ok := callerPC(ol); ok {
+		if src, ok := trace.GetSourceCodeRefAt(pc, traceConfig); ok {
+			return src
+		}
+	}
+	return getSourceCode(skip + 1 + callerSkip(ol))
+}
+
+// callerPC returns the program counter set by WrapAt in ol, if there is one.
+func callerPC(ol []Option) (uintptr, bool) {
+	for _, o := range ol {
+		if pc, ok := o.(atPC); ok {
+			return uintptr(pc), true
+		}
+	}
+	return 0, false
+}
+
+// callerSkip returns the number of frames skipped by WithSkip options in ol.
+func callerSkip(ol []Option) int {
+	var n int
+	for _, o := range ol {
+		if s, ok := o.(withSkip); ok {
+			n += int(s)
+		}
+	}
+	return n
+}
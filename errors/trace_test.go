@@ -2,6 +2,8 @@ package errors
 
 import (
 	"fmt"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -43,14 +45,223 @@ func TestStack(t *testing.T) {
 	goldie.New(t).Assert(t, t.Name(), tr)
 }
 
-func stackCalls(i int) *internal.Error {
+func stackCalls(i int, ol ...Option) *internal.Error {
 	if i == 0 {
-		return New("stack").(*internal.Error)
+		return New("stack", ol...).(*internal.Error)
 	}
-	return stackCalls(i - 1)
+	return stackCalls(i-1, ol...)
 }
 
 func TestGetSourceCode(t *testing.T) {
 	SetTraceConfigTesting(t, TestingConfig)
 	assert.Equal(t, "trace_test.go TestGetSourceCode", getSourceCode(0))
 }
+
+func TestWithStackTraceDepth(t *testing.T) {
+	SetTraceConfigTesting(t, TestingConfig)
+	fullTrace := []string{
+		"trace_test.go stackCalls",
+		"trace_test.go stackCalls",
+		"trace_test.go stackCalls",
+		"trace_test.go TestWithStackTraceDepth",
+	}
+	testCases := []struct {
+		name     string
+		depth    int
+		expTrace []string
+	}{
+		{name: "no limit", depth: 0, expTrace: fullTrace},
+		{name: "negative is no limit", depth: -1, expTrace: fullTrace},
+		{name: "limited", depth: 2, expTrace: fullTrace[:2]},
+		{name: "limit larger than trace", depth: 10, expTrace: fullTrace},
+	}
+	for _, tc := range testCases {
+		err := stackCalls(2, WithStackTraceDepth(tc.depth))
+		assert.Equal(t, tc.expTrace, err.StackTrace, tc.name)
+	}
+}
+
+func TestWithSource(t *testing.T) {
+	SetTraceConfigTesting(t, TestingConfig)
+	err := New("test", WithSource("helper.go", 42)).(*internal.Error)
+	assert.Equal(t, "helper.go:42", err.Source)
+
+	err = Wrap(err, "wrap", WithSource("other.go", 1)).(*internal.Error)
+	assert.Equal(t, "other.go:1", err.Source)
+}
+
+func TestWithoutSource(t *testing.T) {
+	SetTraceConfigTesting(t, TestingConfig)
+	err := New("test", WithoutSource()).(*internal.Error)
+	assert.Empty(t, err.Source)
+	assert.NotEmpty(t, err.StackTrace)
+
+	err = Wrap(err, "wrap").(*internal.Error)
+	assert.Equal(t, "trace_test.go TestWithoutSource", err.Source)
+
+	err = Wrap(err, "wrap", WithoutSource()).(*internal.Error)
+	assert.Empty(t, err.Source)
+
+	sentinel := New("sentinel", WithoutStackTrace())
+	err = Wrap(sentinel, "", WithoutSource()).(*internal.Error)
+	assert.Empty(t, err.Source)
+	assert.NotEmpty(t, err.StackTrace)
+
+	// Options are applied in order
+	err = New("test", WithoutSource(), WithSource("helper.go", 1)).(*internal.Error)
+	assert.Equal(t, "helper.go:1", err.Source)
+}
+
+func newInHelper(msg string, ol ...Option) error {
+	return New(msg, ol...)
+}
+
+func wrapInHelper(err error, ol ...Option) error {
+	return Wrap(err, "wrap", ol...)
+}
+
+func TestWithSkip(t *testing.T) {
+	SetTraceConfigTesting(t, TestingConfig)
+
+	err := newInHelper("test").(*internal.Error)
+	assert.Equal(t, "trace_test.go newInHelper", err.Source)
+	assert.Equal(t, "trace_test.go newInHelper", err.StackTrace[0])
+
+	err = newInHelper("test", WithSkip(1)).(*internal.Error)
+	assert.Equal(t, "trace_test.go TestWithSkip", err.Source)
+	assert.Equal(t, []string{"trace_test.go TestWithSkip"}, err.StackTrace)
+
+	err = wrapInHelper(fmt.Errorf("stdlib"), WithSkip(1)).(*internal.Error)
+	assert.Equal(t, "trace_test.go TestWithSkip", err.Source)
+	assert.Equal(t, []string{"trace_test.go TestWithSkip"}, err.StackTrace)
+
+	sentinel := New("sentinel", WithoutStackTrace())
+	err = Wrap(sentinel, "", WithSkip(0), WithSkip(0)).(*internal.Error)
+	assert.Equal(t, "trace_test.go TestWithSkip", err.Source)
+}
+
+// wrapAtCaller wraps err at the call to wrapAtCaller, as if it had been
+// wrapped by its caller.
+func wrapAtCaller(err error, ol ...Option) error {
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:])
+	return wrapAtInHelper(err, pcs[0], ol...)
+}
+
+func wrapAtInHelper(err error, pc uintptr, ol ...Option) error {
+	return WrapAt(err, "wrap", pc, ol...)
+}
+
+func pcInHelper() uintptr {
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	return pcs[0]
+}
+
+func TestWrapAt(t *testing.T) {
+	SetTraceConfigTesting(t, TestingConfig)
+	SetCodeGeneratorTesting(t, func(msg, file string, line int) string {
+		return msg + ":" + strconv.Itoa(line)
+	})
+
+	_, _, line, _ := runtime.Caller(0)
+	err := wrapAtCaller(fmt.Errorf("stdlib")).(*internal.Error)
+	assert.Equal(t, "trace_test.go TestWrapAt", err.Source)
+	assert.Equal(t, []string{"trace_test.go TestWrapAt"}, err.StackTrace)
+	assert.Equal(t, "wrap:"+strconv.Itoa(line+1), err.Code)
+
+	// Errors with a trace only get the source
+	err = wrapAtCaller(New("test")).(*internal.Error)
+	assert.Equal(t, "trace_test.go TestWrapAt", err.Source)
+	assert.Empty(t, err.StackTrace)
+
+	err = wrapAtCaller(fmt.Errorf("stdlib"), WithoutSource()).(*internal.Error)
+	assert.Empty(t, err.Source)
+	assert.Equal(t, []string{"trace_test.go TestWrapAt"}, err.StackTrace)
+
+	// A pc which isn't on the stack anymore falls back to the caller of WrapAt
+	pc := pcInHelper()
+	err = wrapAtInHelper(fmt.Errorf("stdlib"), pc).(*internal.Error)
+	assert.Equal(t, "trace_test.go wrapAtInHelper", err.Source)
+	assert.Equal(t, []string{"trace_test.go wrapAtInHelper", "trace_test.go TestWrapAt"}, err.StackTrace)
+
+	assert.Nil(t, WrapAt(nil, "wrap", pc))
+}
+
+func TestMust(t *testing.T) {
+	SetTraceConfigTesting(t, TestingConfig)
+
+	assert.Equal(t, 1, Must(1, nil))
+	assert.NotPanics(t, func() { Must0(nil) })
+
+	recovered := func(f func()) (err *internal.Error) {
+		defer func() {
+			err = recover().(*internal.Error)
+		}()
+		f()
+		return nil
+	}
+
+	stdlib := fmt.Errorf("stdlib")
+	err := recovered(func() { Must(1, stdlib) })
+	assert.True(t, Is(err, stdlib))
+	assert.Equal(t, "stdlib", err.Error())
+	assert.Equal(t, "trace_test.go TestMust.func3", err.Source)
+	assert.Equal(t, []string{"trace_test.go TestMust"}, err.StackTrace)
+
+	sentinel := New("sentinel", WithoutStackTrace())
+	err = recovered(func() { Must0(sentinel) })
+	assert.True(t, Is(err, sentinel))
+	assert.Equal(t, "trace_test.go TestMust.func4", err.Source)
+	assert.Equal(t, []string{"trace_test.go TestMust"}, err.StackTrace)
+
+	// Errors with a trace keep it
+	withTrace := newInHelper("helper")
+	err = recovered(func() { Must0(withTrace) })
+	assert.Equal(t, withTrace, err)
+}
+
+var stdlibErr *internal.Error
+
+func newErrFromStdlib(r rune) rune {
+	stdlibErr = New("from stdlib").(*internal.Error)
+	return r
+}
+
+func TestSetTraceMode(t *testing.T) {
+	SetTraceConfigTesting(t, TestingConfig)
+	t.Cleanup(func() { SetTraceMode(TraceModeFull) })
+
+	SetTraceMode(TraceModeFull)
+	assert.Len(t, stackCalls(8).StackTrace, 10)
+	strings.Map(newErrFromStdlib, "a")
+	assert.Equal(t, []string{
+		"trace_test.go newErrFromStdlib",
+		"strings.go Map",
+		"trace_test.go TestSetTraceMode",
+	}, stdlibErr.StackTrace)
+
+	SetTraceMode(TraceModeCompact)
+	assert.Equal(t, []string{
+		"trace_test.go stackCalls",
+		"trace_test.go stackCalls",
+		"trace_test.go stackCalls",
+		"trace_test.go stackCalls",
+		"trace_test.go stackCalls",
+	}, stackCalls(8).StackTrace)
+	strings.Map(newErrFromStdlib, "a")
+	assert.Equal(t, []string{
+		"trace_test.go newErrFromStdlib",
+		"trace_test.go TestSetTraceMode",
+	}, stdlibErr.StackTrace)
+	assert.Len(t, stackCalls(8, WithStackTraceDepth(2)).StackTrace, 2)
+
+	SetTraceMode(TraceModeNone)
+	err := stackCalls(8)
+	assert.Empty(t, err.StackTrace)
+	assert.NotEmpty(t, err.Binary)
+
+	// Wrapping doesn't try to add a trace when there's none
+	wrapped := Wrap(err, "").(*internal.Error)
+	assert.Same(t, err, wrapped)
+}
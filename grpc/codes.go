@@ -0,0 +1,51 @@
+package grpc
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/peterlabuschagne/jettison/errors"
+	"github.com/peterlabuschagne/jettison/internal"
+)
+
+var codeRegistry = struct {
+	sync.RWMutex
+	codes map[string]codes.Code
+}{codes: make(map[string]codes.Code)}
+
+// RegisterCode maps a jettison error code to a gRPC status code. Errors
+// returned from servers using the jettison interceptors are sent with the
+// gRPC code registered for their code. Codes registered with
+// codes.DeadlineExceeded are also registered with errors.RegisterTimeoutCode,
+// so that errors.IsTimeout matches them. It is typically called during init.
+func RegisterCode(jettisonCode string, grpcCode codes.Code) {
+	if grpcCode == codes.DeadlineExceeded {
+		errors.RegisterTimeoutCode(jettisonCode)
+	}
+	codeRegistry.Lock()
+	defer codeRegistry.Unlock()
+	codeRegistry.codes[jettisonCode] = grpcCode
+}
+
+// GRPCCode returns the gRPC status code registered for the code of the latest
+// wrapped error in the err error tree which has a code.
+// It returns codes.Unknown if that code isn't registered.
+func GRPCCode(err error) codes.Code {
+	var code string
+	errors.Walk(err, func(err error) bool {
+		je, ok := err.(*internal.Error)
+		if ok && je.Code != "" {
+			code = je.Code
+			return false
+		}
+		return true
+	})
+	codeRegistry.RLock()
+	defer codeRegistry.RUnlock()
+	c, ok := codeRegistry.codes[code]
+	if !ok {
+		return codes.Unknown
+	}
+	return c
+}
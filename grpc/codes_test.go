@@ -0,0 +1,85 @@
+package grpc
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/peterlabuschagne/jettison/errors"
+)
+
+func TestGRPCCode(t *testing.T) {
+	RegisterCode("test_not_found", codes.NotFound)
+	RegisterCode("test_invalid", codes.InvalidArgument)
+
+	testCases := []struct {
+		name    string
+		err     error
+		expCode codes.Code
+	}{
+		{name: "nil error", expCode: codes.Unknown},
+		{name: "non-jettison error", err: io.EOF, expCode: codes.Unknown},
+		{
+			name:    "unregistered code",
+			err:     errors.New("test", errors.WithCode("test_unregistered")),
+			expCode: codes.Unknown,
+		},
+		{
+			name:    "registered code",
+			err:     errors.New("test", errors.WithCode("test_not_found")),
+			expCode: codes.NotFound,
+		},
+		{
+			name:    "wrapped registered code",
+			err:     errors.Wrap(errors.New("test", errors.WithCode("test_not_found")), "wrap"),
+			expCode: codes.NotFound,
+		},
+		{
+			name: "top-most code is used",
+			err: errors.Wrap(
+				errors.New("test", errors.WithCode("test_not_found")),
+				"wrap", errors.WithCode("test_invalid"),
+			),
+			expCode: codes.InvalidArgument,
+		},
+		{
+			name: "top-most code is unregistered",
+			err: errors.Wrap(
+				errors.New("test", errors.WithCode("test_not_found")),
+				"wrap", errors.WithCode("test_unregistered"),
+			),
+			expCode: codes.Unknown,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expCode, GRPCCode(tc.err))
+		})
+	}
+}
+
+func TestStatusUsesRegisteredCode(t *testing.T) {
+	RegisterCode("test_unavailable", codes.Unavailable)
+
+	s := toStatus(errors.New("test", errors.WithCode("test_unavailable")), serverConfig{})
+	assert.Equal(t, codes.Unavailable, s.Code())
+	assert.Equal(t, "test", s.Message())
+}
+
+func TestIsTimeoutRegisteredCode(t *testing.T) {
+	RegisterCode("test_deadline", codes.DeadlineExceeded)
+	RegisterCode("test_not_timeout", codes.Unavailable)
+
+	assert.True(t, errors.IsTimeout(errors.New("test", errors.WithCode("test_deadline"))))
+	assert.False(t, errors.IsTimeout(errors.New("test", errors.WithCode("test_not_timeout"))))
+
+	// Errors received over gRPC match by their status
+	err := FromError(status.Error(codes.DeadlineExceeded, "too slow"))
+	assert.True(t, errors.IsTimeout(errors.Wrap(err, "wrap")))
+	err = FromError(status.Error(codes.Unavailable, "down"))
+	assert.False(t, errors.IsTimeout(err))
+}
@@ -45,14 +45,23 @@ func incomingContext(ctx context.Context) context.Context {
 	return log.ContextWithKeyValues(ctx, kvs)
 }
 
-func outgoingContext(ctx context.Context) context.Context {
+// outgoingContext returns ctx with its jettison key-values added to the
+// outgoing metadata, limited to those allowed by cfg.
+func outgoingContext(ctx context.Context, cfg clientConfig) context.Context {
 	kvs := log.ContextKeyValues(ctx)
 	if len(kvs) == 0 {
 		return ctx
 	}
 	args := make([]string, 0, len(kvs)*2)
 	for _, kv := range kvs {
+		if !cfg.propagated(kv.Key) {
+			continue
+		}
+		kv = kv.Resolve()
 		args = append(args, toJettisonKey(kv.Key), kv.Value)
 	}
+	if len(args) == 0 {
+		return ctx
+	}
 	return metadata.AppendToOutgoingContext(ctx, args...)
 }
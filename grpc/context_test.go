@@ -60,7 +60,7 @@ func TestOutgoingContext(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			ctx := outgoingContext(tc.ctx)
+			ctx := outgoingContext(tc.ctx, clientConfig{})
 			md, _ := metadata.FromOutgoingContext(ctx)
 			assert.Equal(t, tc.expMD, md)
 		})
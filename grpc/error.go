@@ -8,6 +8,7 @@ import (
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/peterlabuschagne/jettison/errors"
 	"github.com/peterlabuschagne/jettison/grpc/internal/jettisonpb"
@@ -49,7 +50,11 @@ func (g Error) Unwrap() error {
 // Wrap will construct an Error that will serialise err when
 // needed by gRPC by exposing the GRPCStatus method
 func Wrap(err error) Error {
-	return Error{s: toStatus(err), err: err}
+	return wrap(err, serverConfig{})
+}
+
+func wrap(err error, cfg serverConfig) Error {
+	return Error{s: toStatus(err, cfg), err: err}
 }
 
 // FromError will de-serialise the details from the status
@@ -77,22 +82,27 @@ func FromError(err error) error {
 // toStatus marshals the given jettison error into a *grpc.Status object,
 // with a message given by the most recently wrapped error in the list of
 // hops.
-func toStatus(err error) *status.Status {
+func toStatus(err error, cfg serverConfig) *status.Status {
 	s, ok := status.FromError(err)
 	if !ok {
-		c := codes.Unknown
+		var c codes.Code
 		var msg string
 		if errors.Is(err, context.Canceled) {
 			c = codes.Canceled
 		} else if errors.Is(err, context.DeadlineExceeded) {
 			c = codes.DeadlineExceeded
 		} else {
+			c = GRPCCode(err)
 			msg = err.Error()
 		}
 		s = status.New(c, msg)
 	}
 
-	withWrap, err := s.WithDetails(errorToProto(err))
+	we := errorToProto(err)
+	if cfg.withoutStackTraces {
+		removeStackTraces(we)
+	}
+	withWrap, err := limitStatus(s, we, cfg.maxStatusBytes)
 	if err != nil {
 		log.Printf("jettison/errors: Failed to add WrappedError to status: %v", err)
 	} else {
@@ -124,6 +134,10 @@ func errorFromProto(we *jettisonpb.WrappedError) *internal.Error {
 		Source:     we.Source,
 		StackTrace: we.StackTrace,
 		KV:         kvFromProto(we.KeyValues),
+		Tags:       we.Tags,
+	}
+	if we.Timestamp != nil {
+		je.Timestamp = we.Timestamp.AsTime()
 	}
 	if len(we.JoinedErrors) > 0 {
 		var errs []error
@@ -156,6 +170,12 @@ func errorToProto(err error) *jettisonpb.WrappedError {
 			}
 		}
 		we.KeyValues = kvToProto(je.KV)
+		for _, tag := range je.Tags {
+			we.Tags = append(we.Tags, removeNonUTF8(tag))
+		}
+		if !je.Timestamp.IsZero() {
+			we.Timestamp = timestamppb.New(je.Timestamp)
+		}
 	} else {
 		we.Message = removeNonUTF8(err.Error())
 	}
@@ -175,7 +195,7 @@ func kvToProto(kvs []models.KeyValue) []*jettisonpb.KeyValue {
 		return nil
 	}
 	res := make([]*jettisonpb.KeyValue, 0, len(kvs))
-	for _, kv := range kvs {
+	for _, kv := range models.ResolveAll(kvs) {
 		res = append(res, &jettisonpb.KeyValue{
 			Key:   removeNonUTF8(kv.Key),
 			Value: removeNonUTF8(kv.Value),
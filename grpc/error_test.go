@@ -7,12 +7,14 @@ import (
 	"io"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/peterlabuschagne/jettison/errors"
 	"github.com/peterlabuschagne/jettison/grpc/internal/jettisonpb"
@@ -69,6 +71,9 @@ func TestFromStatus(t *testing.T) {
 }
 
 func TestToProto(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	errors.SetClockTesting(t, func() time.Time { return ts })
+
 	testCases := []struct {
 		name     string
 		err      error
@@ -93,6 +98,7 @@ func TestToProto(t *testing.T) {
 					Message: "EOF",
 				},
 				KeyValues: []*jettisonpb.KeyValue{{Key: "key", Value: "value"}},
+				Timestamp: timestamppb.New(ts),
 			},
 		},
 		{
@@ -112,6 +118,8 @@ func TestToProto(t *testing.T) {
 
 func TestToFromStatus(t *testing.T) {
 	errors.SetTraceConfigTesting(t, errors.TestingConfig)
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	errors.SetClockTesting(t, func() time.Time { return ts })
 
 	getStrconvErr := func() error {
 		_, err := strconv.Atoi("nan")
@@ -130,8 +138,9 @@ func TestToFromStatus(t *testing.T) {
 				errors.WithoutStackTrace(),
 			),
 			expJetty: internal.Error{
-				Message: "msg",
-				Source:  "error_test.go TestToFromStatus",
+				Message:   "msg",
+				Source:    "error_test.go TestToFromStatus",
+				Timestamp: ts,
 				KV: []models.KeyValue{
 					{Key: "key", Value: "value"},
 				},
@@ -143,8 +152,9 @@ func TestToFromStatus(t *testing.T) {
 				j.MKV{"key1": "value1", "key2": "value2"},
 			),
 			expJetty: internal.Error{
-				Message: "msg",
-				Source:  "error_test.go TestToFromStatus",
+				Message:   "msg",
+				Source:    "error_test.go TestToFromStatus",
+				Timestamp: ts,
 				KV: []models.KeyValue{
 					{Key: "key1", Value: "value1"},
 					{Key: "key2", Value: "value2"},
@@ -158,11 +168,13 @@ func TestToFromStatus(t *testing.T) {
 				"outer", errors.WithoutStackTrace(),
 			),
 			expJetty: internal.Error{
-				Message: "outer",
-				Source:  "error_test.go TestToFromStatus",
+				Message:   "outer",
+				Source:    "error_test.go TestToFromStatus",
+				Timestamp: ts,
 				Err: &internal.Error{
-					Message: "inner",
-					Source:  "error_test.go TestToFromStatus",
+					Message:   "inner",
+					Source:    "error_test.go TestToFromStatus",
+					Timestamp: ts,
 				},
 			},
 		},
@@ -173,8 +185,9 @@ func TestToFromStatus(t *testing.T) {
 				"jetty", errors.WithoutStackTrace(),
 			),
 			expJetty: internal.Error{
-				Message: "jetty",
-				Source:  "error_test.go TestToFromStatus",
+				Message:   "jetty",
+				Source:    "error_test.go TestToFromStatus",
+				Timestamp: ts,
 				Err: &internal.Error{
 					Message: "unexpected EOF",
 				},
@@ -198,6 +211,7 @@ func TestToFromStatus(t *testing.T) {
 				KV: []models.KeyValue{
 					{Key: "k1", Value: "v1"},
 				},
+				Tags: []string{"t1", "t2"},
 				Err: &internal.Error{
 					Message:    "inner msg",
 					Binary:     "binary2",
@@ -207,6 +221,7 @@ func TestToFromStatus(t *testing.T) {
 					KV: []models.KeyValue{
 						{Key: "k2", Value: "v2"},
 					},
+					Tags: []string{"t3"},
 				},
 			},
 			expJetty: internal.Error{
@@ -218,6 +233,7 @@ func TestToFromStatus(t *testing.T) {
 				KV: []models.KeyValue{
 					{Key: "k1", Value: "v1"},
 				},
+				Tags: []string{"t1", "t2"},
 				Err: &internal.Error{
 					Message:    "inner msg",
 					Binary:     "binary2",
@@ -227,6 +243,7 @@ func TestToFromStatus(t *testing.T) {
 					KV: []models.KeyValue{
 						{Key: "k2", Value: "v2"},
 					},
+					Tags: []string{"t3"},
 				},
 			},
 		},
@@ -244,6 +261,7 @@ func TestToFromStatus(t *testing.T) {
 						Value: "value with \xc5",
 					},
 				},
+				Tags: []string{"tag \xc5"},
 			},
 			expJetty: internal.Error{
 				Message:    "msg[snip]",
@@ -257,14 +275,16 @@ func TestToFromStatus(t *testing.T) {
 						Value: "value with [snip]",
 					},
 				},
+				Tags: []string{"tag [snip]"},
 			},
 		},
 		{
 			name: "non-jettison but can unwrap, results in some redundant messages",
 			err:  errors.Wrap(getStrconvErr(), "wrapper", errors.WithoutStackTrace()),
 			expJetty: internal.Error{
-				Message: "wrapper",
-				Source:  "error_test.go TestToFromStatus",
+				Message:   "wrapper",
+				Source:    "error_test.go TestToFromStatus",
+				Timestamp: ts,
 				Err: &internal.Error{
 					Message: "strconv.Atoi: parsing \"nan\": invalid syntax",
 					Err: &internal.Error{
@@ -282,7 +302,8 @@ func TestToFromStatus(t *testing.T) {
 			name: "wrapped context deadline exceeded",
 			err:  errors.Wrap(context.DeadlineExceeded, "", errors.WithoutStackTrace()),
 			expJetty: internal.Error{
-				Source: "error_test.go TestToFromStatus",
+				Source:    "error_test.go TestToFromStatus",
+				Timestamp: ts,
 				Err: &internal.Error{
 					Message: context.DeadlineExceeded.Error(),
 				},
@@ -292,7 +313,7 @@ func TestToFromStatus(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			st := toStatus(tc.err)
+			st := toStatus(tc.err, serverConfig{})
 			je, ok := fromStatus(st)
 			assert.True(t, ok)
 			errorEqual(t, &tc.expJetty, je)
@@ -307,6 +328,8 @@ func errorEqual(t *testing.T, exp, act *internal.Error) {
 	assert.Equal(t, exp.Code, act.Code)
 	assert.Equal(t, exp.Source, act.Source)
 	assert.Equal(t, exp.KV, act.KV)
+	assert.Equal(t, exp.Tags, act.Tags)
+	assert.True(t, exp.Timestamp.Equal(act.Timestamp), "timestamp %v != %v", exp.Timestamp, act.Timestamp)
 	nextJe, ok := exp.Err.(*internal.Error)
 	if ok {
 		errorEqual(t, nextJe, act.Err.(*internal.Error))
@@ -341,6 +364,11 @@ func TestGRPCStatus(t *testing.T) {
 			err:       errors.Wrap(errors.Wrap(context.Canceled, ""), ""),
 			expStatus: status.New(codes.Canceled, ""),
 		},
+		{
+			name:      "non-jettison error",
+			err:       io.EOF,
+			expStatus: status.New(codes.Unknown, "EOF"),
+		},
 		{
 			name:      "status error",
 			err:       status.Error(codes.Unavailable, "oh no!"),
@@ -350,7 +378,7 @@ func TestGRPCStatus(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			e := outgoingError(tc.err)
+			e := outgoingError(tc.err, serverConfig{})
 			stater, ok := e.(interface{ GRPCStatus() *status.Status })
 			require.True(t, ok)
 
@@ -9,7 +9,7 @@ import (
 )
 
 // UnaryClientInterceptor intercepts errors, de-serialising any
-// // WrappedErrors we find and unpacking any context jettison key-values.
+// WrappedErrors we find and unpacking any context jettison key-values.
 func UnaryClientInterceptor(ctx context.Context,
 	method string,
 	req, reply any,
@@ -17,12 +17,16 @@ func UnaryClientInterceptor(ctx context.Context,
 	invoker grpc.UnaryInvoker,
 	opts ...grpc.CallOption,
 ) error {
-	err := invoker(outgoingContext(ctx), method, req, reply, cc, opts...)
+	err := invoker(outgoingContext(ctx, clientConfig{}), method, req, reply, cc, opts...)
 	return incomingError(err)
 }
 
 // StreamClientInterceptor intercepts errors, de-serialising any
 // WrappedErrors we find and unpacking any context jettison key-values.
+//
+// Jettison key-values in ctx are sent as metadata when the stream is created.
+// gRPC metadata can't be changed once a stream has started, so key-values
+// added to the context afterwards are not propagated to the server.
 func StreamClientInterceptor(ctx context.Context,
 	desc *grpc.StreamDesc,
 	cc *grpc.ClientConn,
@@ -30,13 +34,139 @@ func StreamClientInterceptor(ctx context.Context,
 	streamer grpc.Streamer,
 	opts ...grpc.CallOption,
 ) (grpc.ClientStream, error) {
-	res, err := streamer(outgoingContext(ctx), desc, cc, method, opts...)
+	return streamClient(outgoingContext(ctx, clientConfig{}), desc, cc, method, streamer, opts...)
+}
+
+func streamClient(ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	res, err := streamer(ctx, desc, cc, method, opts...)
 	if err != nil {
 		return nil, incomingError(err)
 	}
 	return &clientStream{ClientStream: res}, nil
 }
 
+// ClientOption configures the client interceptors returned by
+// NewUnaryClientInterceptor and NewStreamClientInterceptor.
+type ClientOption func(*clientConfig)
+
+type clientConfig struct {
+	// contextKeys are the context key-values sent to servers,
+	// all of them are sent if it's nil
+	contextKeys map[string]bool
+}
+
+func (c clientConfig) propagated(key string) bool {
+	return c.contextKeys == nil || c.contextKeys[key]
+}
+
+// WithContextKeys limits the jettison key-values in the context which are
+// sent to servers to those with the given keys, e.g. a tenant or request ID,
+// rather than all of them. The server interceptors add the key-values to the
+// handler's context, so they're included in the server's logs.
+// Keys are sent as gRPC metadata, which only allows lower case keys.
+//
+//	grpc.WithUnaryInterceptor(jetgrpc.NewUnaryClientInterceptor(
+//	  jetgrpc.WithContextKeys("tenant", "request_id"),
+//	))
+func WithContextKeys(keys ...string) ClientOption {
+	return func(c *clientConfig) {
+		if c.contextKeys == nil {
+			c.contextKeys = make(map[string]bool, len(keys))
+		}
+		for _, k := range keys {
+			c.contextKeys[k] = true
+		}
+	}
+}
+
+// NewUnaryClientInterceptor returns a UnaryClientInterceptor configured with
+// the given options.
+func NewUnaryClientInterceptor(opts ...ClientOption) grpc.UnaryClientInterceptor {
+	cfg := newClientConfig(opts)
+	return func(ctx context.Context, method string, req, reply any,
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		err := invoker(outgoingContext(ctx, cfg), method, req, reply, cc, opts...)
+		return incomingError(err)
+	}
+}
+
+// NewStreamClientInterceptor returns a StreamClientInterceptor configured
+// with the given options.
+func NewStreamClientInterceptor(opts ...ClientOption) grpc.StreamClientInterceptor {
+	cfg := newClientConfig(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+		method string, streamer grpc.Streamer, opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamClient(outgoingContext(ctx, cfg), desc, cc, method, streamer, opts...)
+	}
+}
+
+func newClientConfig(opts []ClientOption) clientConfig {
+	var cfg clientConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// ServerOption configures the server interceptors returned by
+// NewUnaryServerInterceptor and NewStreamServerInterceptor.
+type ServerOption func(*serverConfig)
+
+type serverConfig struct {
+	withoutStackTraces bool
+	maxStatusBytes     int
+}
+
+// WithoutStackTraces removes the stack traces from errors sent to clients,
+// e.g. to avoid exposing internal paths to less trusted clients or to reduce
+// the size of errors. The messages, codes and key/values of errors are still
+// sent, so clients can still match errors with errors.Is and errors.IsCode.
+func WithoutStackTraces() ServerOption {
+	return func(c *serverConfig) {
+		c.withoutStackTraces = true
+	}
+}
+
+// NewUnaryServerInterceptor returns a UnaryServerInterceptor configured with
+// the given options.
+//
+//	grpc.NewServer(grpc.UnaryInterceptor(
+//	  jetgrpc.NewUnaryServerInterceptor(jetgrpc.WithoutStackTraces()),
+//	))
+func NewUnaryServerInterceptor(opts ...ServerOption) grpc.UnaryServerInterceptor {
+	cfg := newServerConfig(opts)
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		a, err := handler(incomingContext(ctx), req)
+		return a, outgoingError(err, cfg)
+	}
+}
+
+// NewStreamServerInterceptor returns a StreamServerInterceptor configured
+// with the given options.
+func NewStreamServerInterceptor(opts ...ServerOption) grpc.StreamServerInterceptor {
+	cfg := newServerConfig(opts)
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: incomingContext(ss.Context())})
+		return outgoingError(err, cfg)
+	}
+}
+
+func newServerConfig(opts []ServerOption) serverConfig {
+	var cfg serverConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return cfg
+}
+
 // UnaryServerInterceptor intercepts errors, de-serialising any
 // WrappedErrors we find and unpacking any context jettison key-values.
 func UnaryServerInterceptor(ctx context.Context,
@@ -45,11 +175,15 @@ func UnaryServerInterceptor(ctx context.Context,
 	handler grpc.UnaryHandler,
 ) (any, error) {
 	a, err := handler(incomingContext(ctx), req)
-	return a, outgoingError(err)
+	return a, outgoingError(err, serverConfig{})
 }
 
 // StreamServerInterceptor intercepts errors, de-serialising any
 // WrappedErrors we find and unpacking any context jettison key-values.
+//
+// The key-values sent by the client when the stream was created are available
+// from the stream's context for the lifetime of the stream, see
+// log.ContextKeyValues.
 func StreamServerInterceptor(
 	srv any,
 	ss grpc.ServerStream,
@@ -57,7 +191,7 @@ func StreamServerInterceptor(
 	handler grpc.StreamHandler,
 ) error {
 	err := handler(srv, &serverStream{ServerStream: ss, ctx: incomingContext(ss.Context())})
-	return outgoingError(err)
+	return outgoingError(err, serverConfig{})
 }
 
 // incomingError converts all non-nil errors into jettison errors.
@@ -70,11 +204,11 @@ func incomingError(err error) error {
 }
 
 // outgoingError converts any err into one that will include more details when sent over GRPC
-func outgoingError(err error) error {
+func outgoingError(err error, cfg serverConfig) error {
 	if err == nil {
 		return nil
 	}
-	return Wrap(err)
+	return wrap(err, cfg)
 }
 
 type serverStream struct {
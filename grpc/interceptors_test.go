@@ -4,10 +4,18 @@ import (
 	"context"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/peterlabuschagne/jettison/errors"
+	"github.com/peterlabuschagne/jettison/j"
 	"github.com/peterlabuschagne/jettison/jtest"
+	"github.com/peterlabuschagne/jettison/log"
+	"github.com/peterlabuschagne/jettison/models"
 )
 
 func TestErrIntercept(t *testing.T) {
@@ -36,3 +44,98 @@ func TestErrIntercept(t *testing.T) {
 		})
 	}
 }
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss testServerStream) Context() context.Context {
+	return ss.ctx
+}
+
+func TestStreamServerInterceptorContext(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("__jettison__hello", "world"),
+	)
+
+	var kvs []models.KeyValue
+	err := StreamServerInterceptor(nil, testServerStream{ctx: ctx}, nil,
+		func(_ any, ss grpc.ServerStream) error {
+			kvs = log.ContextKeyValues(ss.Context())
+			return nil
+		},
+	)
+	jtest.RequireNil(t, err)
+	assert.Equal(t, []models.KeyValue{{Key: "hello", Value: "world"}}, kvs)
+}
+
+func TestWithoutStackTraces(t *testing.T) {
+	handlerErr := errors.Wrap(errors.New("inner", j.C("inner")), "outer", j.KV("k", "v"))
+	handler := func(context.Context, any) (any, error) {
+		return nil, handlerErr
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []ServerOption
+		expTrace bool
+	}{
+		{name: "default", expTrace: true},
+		{name: "without stack traces", opts: []ServerOption{WithoutStackTraces()}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewUnaryServerInterceptor(tc.opts...)(context.Background(), nil, nil, handler)
+			s, ok := status.FromError(err)
+			require.True(t, ok)
+			je, ok := fromStatus(s)
+			require.True(t, ok)
+
+			assert.Equal(t, "outer: inner", je.Error())
+			assert.True(t, errors.IsCode(je, "inner"))
+			assert.Equal(t, map[string]string{"k": "v"}, errors.GetKeyValues(je))
+			_, trace, _ := errors.GetLastStackTrace(je)
+			assert.Equal(t, tc.expTrace, len(trace) > 0)
+		})
+	}
+}
+
+func TestStreamClientInterceptorContext(t *testing.T) {
+	ctx := log.ContextWith(context.Background(), j.KV("hello", "world"))
+
+	var md metadata.MD
+	_, err := StreamClientInterceptor(ctx, nil, nil, "",
+		func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+			md, _ = metadata.FromOutgoingContext(ctx)
+			return nil, nil
+		},
+	)
+	jtest.RequireNil(t, err)
+	assert.Equal(t, metadata.Pairs("__jettison__hello", "world"), md)
+}
+
+func TestWithContextKeys(t *testing.T) {
+	ctx := log.ContextWith(context.Background(), j.MKS{"tenant": "acme", "secret": "hunter2"})
+
+	var md metadata.MD
+	_, err := NewStreamClientInterceptor(WithContextKeys("tenant"))(ctx, nil, nil, "",
+		func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+			md, _ = metadata.FromOutgoingContext(ctx)
+			return nil, nil
+		},
+	)
+	jtest.RequireNil(t, err)
+	assert.Equal(t, metadata.Pairs("__jettison__tenant", "acme"), md)
+
+	md = nil
+	err = NewUnaryClientInterceptor(WithContextKeys("other"))(ctx, "", nil, nil, nil,
+		func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+			md, _ = metadata.FromOutgoingContext(ctx)
+			return nil
+		},
+	)
+	jtest.RequireNil(t, err)
+	assert.Nil(t, md)
+}
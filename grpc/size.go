@@ -0,0 +1,138 @@
+package grpc
+
+import (
+	"log"
+	"strings"
+
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/peterlabuschagne/jettison/grpc/internal/jettisonpb"
+)
+
+// TruncatedKey is the key/value added to errors which were truncated to fit
+// within the limit set by WithMaxStatusBytes.
+const TruncatedKey = "jettison_truncated"
+
+// WithMaxStatusBytes limits the size of the gRPC status sent for errors
+// returned by the server interceptors. The status, including the encoded
+// jettison error, is sent in the response trailers and an RPC fails with
+// a transport error if they exceed the peer's header size limit, which is
+// often far smaller than gRPC's default, e.g. behind a proxy. n <= 0 means
+// no limit which is the default.
+//
+// Errors larger than the limit are truncated by removing their stack traces,
+// then their oldest errors, and finally the key/values of the most recent
+// error. The most recent message and code are always kept, the code being
+// moved to the most recent error if it has none, and the TruncatedKey
+// key/value is added. A warning is logged whenever an error is
+// truncated.
+func WithMaxStatusBytes(n int) ServerOption {
+	return func(c *serverConfig) {
+		c.maxStatusBytes = n
+	}
+}
+
+// limitStatus returns s with we as its details, truncated if needed to fit
+// within max bytes, see WithMaxStatusBytes.
+func limitStatus(s *status.Status, we *jettisonpb.WrappedError, max int) (*status.Status, error) {
+	withWrap, err := s.WithDetails(we)
+	if err != nil {
+		return nil, err
+	}
+	size := proto.Size(withWrap.Proto())
+	if max <= 0 || size <= max {
+		return withWrap, nil
+	}
+
+	// Messages of errors with details are replaced by those in the details,
+	// so only a prefix is needed for clients not using the interceptors
+	msg := s.Message()
+	if len(msg) > max/4 {
+		msg = strings.ToValidUTF8(msg[:max/4], "") + "..."
+	}
+	base := status.New(s.Code(), msg)
+
+	we = proto.Clone(we).(*jettisonpb.WrappedError)
+	if we.Code == "" {
+		we.Code = firstCode(we)
+	}
+	we.KeyValues = append(we.KeyValues, &jettisonpb.KeyValue{Key: TruncatedKey, Value: "true"})
+	fits := func() bool {
+		withWrap, err = base.WithDetails(we)
+		return err == nil && proto.Size(withWrap.Proto()) <= max
+	}
+
+	removeStackTraces(we)
+	for depth := errorDepth(we) - 1; !fits() && depth >= 0; depth-- {
+		pruneErrors(we, depth)
+	}
+	if !fits() {
+		we.KeyValues = we.KeyValues[len(we.KeyValues)-1:]
+	}
+	if !fits() {
+		// Even the most recent message and code are too large
+		withWrap = base
+	}
+	log.Printf("jettison/grpc: Truncated error status from %d to %d bytes", size, proto.Size(withWrap.Proto()))
+	return withWrap, nil
+}
+
+// firstCode returns the code of the most recent error in we with a code.
+func firstCode(we *jettisonpb.WrappedError) string {
+	if we == nil {
+		return ""
+	}
+	if we.Code != "" {
+		return we.Code
+	}
+	if c := firstCode(we.WrappedError); c != "" {
+		return c
+	}
+	for _, e := range we.JoinedErrors {
+		if c := firstCode(e); c != "" {
+			return c
+		}
+	}
+	return ""
+}
+
+func removeStackTraces(we *jettisonpb.WrappedError) {
+	if we == nil {
+		return
+	}
+	we.StackTrace = nil
+	removeStackTraces(we.WrappedError)
+	for _, e := range we.JoinedErrors {
+		removeStackTraces(e)
+	}
+}
+
+// errorDepth returns the number of errors in the longest path through we.
+func errorDepth(we *jettisonpb.WrappedError) int {
+	if we == nil {
+		return 0
+	}
+	depth := errorDepth(we.WrappedError)
+	for _, e := range we.JoinedErrors {
+		if d := errorDepth(e); d > depth {
+			depth = d
+		}
+	}
+	return depth + 1
+}
+
+// pruneErrors removes the errors more than depth errors below we.
+func pruneErrors(we *jettisonpb.WrappedError, depth int) {
+	if depth == 0 {
+		we.WrappedError = nil
+		we.JoinedErrors = nil
+		return
+	}
+	if we.WrappedError != nil {
+		pruneErrors(we.WrappedError, depth-1)
+	}
+	for _, e := range we.JoinedErrors {
+		pruneErrors(e, depth-1)
+	}
+}
@@ -0,0 +1,81 @@
+package grpc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/peterlabuschagne/jettison/errors"
+	"github.com/peterlabuschagne/jettison/j"
+)
+
+func TestWithMaxStatusBytes(t *testing.T) {
+	err := sizeTestError()
+	size := proto.Size(toStatus(err, serverConfig{}).Proto())
+
+	testCases := []struct {
+		name     string
+		max      int
+		expMsgs  []string
+		expTrace bool
+	}{
+		{
+			name:     "no limit",
+			expMsgs:  []string{"top", "middle", "root"},
+			expTrace: true,
+		},
+		{
+			name:     "within limit",
+			max:      size,
+			expMsgs:  []string{"top", "middle", "root"},
+			expTrace: true,
+		},
+		{
+			name:    "stack traces removed",
+			max:     650,
+			expMsgs: []string{"top", "middle", "root"},
+		},
+		{
+			name:    "oldest errors removed",
+			max:     300,
+			expMsgs: []string{"top", "middle"},
+		},
+		{
+			name:    "most recent error only",
+			max:     200,
+			expMsgs: []string{"top"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := toStatus(err, newServerConfig([]ServerOption{WithMaxStatusBytes(tc.max)}))
+			if tc.max > 0 {
+				assert.LessOrEqual(t, proto.Size(s.Proto()), tc.max)
+			}
+			je, ok := fromStatus(s)
+			require.True(t, ok)
+			assert.Equal(t, tc.expMsgs, errors.GetMessages(je))
+			assert.True(t, errors.IsCode(je, "root_code"))
+			_, trace, _ := errors.GetLastStackTrace(je)
+			assert.Equal(t, tc.expTrace, len(trace) > 0)
+			if !tc.expTrace {
+				assert.Equal(t, "true", errors.GetKeyValues(je)[TruncatedKey])
+			}
+		})
+	}
+
+	t.Run("details removed", func(t *testing.T) {
+		_, ok := fromStatus(toStatus(err, newServerConfig([]ServerOption{WithMaxStatusBytes(100)})))
+		assert.False(t, ok)
+	})
+}
+
+func sizeTestError() error {
+	err := errors.New("root", j.C("root_code"), errors.WithStackTrace(), j.KV("big", strings.Repeat("x", 200)))
+	err = errors.Wrap(err, "middle", j.KV("k", "v"))
+	return errors.Wrap(err, "top", j.KV("top", "v"))
+}
@@ -4,18 +4,23 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 
 	"github.com/peterlabuschagne/jettison/errors"
+	jetgrpc "github.com/peterlabuschagne/jettison/grpc"
 	"github.com/peterlabuschagne/jettison/grpc/test/testgrpc"
 	"github.com/peterlabuschagne/jettison/grpc/test/testpb"
 	"github.com/peterlabuschagne/jettison/j"
 	"github.com/peterlabuschagne/jettison/jtest"
 	"github.com/peterlabuschagne/jettison/log"
+	"github.com/peterlabuschagne/jettison/models"
+	"github.com/peterlabuschagne/jettison/trace"
 )
 
 func TestNewOverGrpc(t *testing.T) {
@@ -65,6 +70,97 @@ func TestWrapOverGrpc(t *testing.T) {
 	assert.False(t, errors.Is(errFalse, ref))
 }
 
+func TestRoundTripOverGrpc(t *testing.T) {
+	l, err := net.Listen("tcp", "")
+	jtest.RequireNil(t, err)
+	defer l.Close()
+
+	_, stop := testgrpc.NewServer(t, l)
+	defer stop()
+
+	cl, err := testgrpc.NewClient(t, l.Addr().String())
+	jtest.RequireNil(t, err)
+	defer cl.Close()
+
+	err = cl.ErrorWithCode(context.Background(), "round_trip")
+	require.Error(t, err)
+
+	assert.Equal(t, "error with code", err.Error())
+	assert.True(t, errors.IsCode(err, "round_trip"))
+	assert.True(t, errors.Is(err, errors.Code("round_trip")))
+	assert.False(t, errors.Is(err, errors.Code("other")))
+	assert.Equal(t, map[string]string{"hello": "WORLD"}, errors.GetKeyValues(err))
+	// The server and client are the same binary
+	assert.Equal(t, []string{trace.CurrentBinary()}, errors.GetBinaries(err))
+}
+
+func TestOversizedErrorOverGrpc(t *testing.T) {
+	const maxHeaderBytes = 16 << 10
+	call := func(jopts ...jetgrpc.ServerOption) error {
+		l, err := net.Listen("tcp", "")
+		jtest.RequireNil(t, err)
+		defer l.Close()
+
+		_, stop := testgrpc.NewServerWith(t, l, jopts)
+		defer stop()
+
+		cl, err := testgrpc.NewClient(t, l.Addr().String(),
+			grpc.WithMaxHeaderListSize(maxHeaderBytes))
+		jtest.RequireNil(t, err)
+		defer cl.Close()
+
+		return cl.WrapErrorWithCode("oversized", 1000)
+	}
+
+	// Without a limit the trailers are too large and the error is lost
+	err := call()
+	require.Error(t, err)
+	assert.False(t, errors.IsCode(err, "oversized"))
+
+	err = call(jetgrpc.WithMaxStatusBytes(maxHeaderBytes / 2))
+	require.Error(t, err)
+	assert.True(t, errors.IsCode(err, "oversized"))
+	assert.True(t, strings.HasPrefix(err.Error(), "wrap: wrap"))
+	assert.Equal(t, "true", errors.GetKeyValues(err)[jetgrpc.TruncatedKey])
+}
+
+func TestContextKeysOverGrpc(t *testing.T) {
+	logs := log.NewCaptureLogger()
+	log.SetLoggerForTesting(t, logs)
+
+	l, err := net.Listen("tcp", "")
+	jtest.RequireNil(t, err)
+	defer l.Close()
+
+	// Log from a handler, after the jettison interceptor
+	logRequest := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		log.Info(ctx, "request")
+		return handler(ctx, req)
+	}
+	_, stop := testgrpc.NewServer(t, l, grpc.ChainUnaryInterceptor(logRequest))
+	defer stop()
+
+	cl, err := testgrpc.NewClient(t, l.Addr().String(),
+		grpc.WithUnaryInterceptor(jetgrpc.NewUnaryClientInterceptor(
+			jetgrpc.WithContextKeys("tenant", "request_id"),
+		)))
+	jtest.RequireNil(t, err)
+	defer cl.Close()
+
+	ctx := log.ContextWith(context.Background(),
+		j.MKS{"tenant": "acme", "request_id": "123", "secret": "hunter2"})
+	err = cl.ErrorWithCode(ctx, "1")
+	require.Error(t, err)
+
+	entries := logs.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "request", entries[0].Message)
+	assert.Equal(t, []models.KeyValue{
+		{Key: "request_id", Value: "123"},
+		{Key: "tenant", Value: "acme"},
+	}, entries[0].Parameters)
+}
+
 func TestClientStacktrace(t *testing.T) {
 	errors.SetTraceConfigTesting(t, errors.TestingConfig)
 	l, err := net.Listen("tcp", "")
@@ -16,10 +16,13 @@ type Client struct {
 	conn *grpc.ClientConn
 }
 
-func NewClient(t *testing.T, addr string) (*Client, error) {
-	conn, err := grpc.Dial(addr, grpc.WithInsecure(),
+func NewClient(t *testing.T, addr string, opts ...grpc.DialOption) (*Client, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithInsecure(),
 		grpc.WithUnaryInterceptor(jetgrpc.UnaryClientInterceptor),
-		grpc.WithStreamInterceptor(jetgrpc.StreamClientInterceptor))
+		grpc.WithStreamInterceptor(jetgrpc.StreamClientInterceptor),
+	}, opts...)
+	conn, err := grpc.Dial(addr, opts...)
 	if err != nil {
 		return nil, err
 	}
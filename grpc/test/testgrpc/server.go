@@ -15,10 +15,18 @@ import (
 
 type Server struct{}
 
-func NewServer(t *testing.T, l net.Listener) (*Server, func()) {
-	grpcSrv := grpc.NewServer(
-		grpc.UnaryInterceptor(jetgrpc.UnaryServerInterceptor),
-		grpc.StreamInterceptor(jetgrpc.StreamServerInterceptor))
+func NewServer(t *testing.T, l net.Listener, opts ...grpc.ServerOption) (*Server, func()) {
+	return NewServerWith(t, l, nil, opts...)
+}
+
+// NewServerWith is like NewServer, but configures the jettison interceptors
+// with jopts.
+func NewServerWith(t *testing.T, l net.Listener, jopts []jetgrpc.ServerOption, opts ...grpc.ServerOption) (*Server, func()) {
+	opts = append([]grpc.ServerOption{
+		grpc.UnaryInterceptor(jetgrpc.NewUnaryServerInterceptor(jopts...)),
+		grpc.StreamInterceptor(jetgrpc.NewStreamServerInterceptor(jopts...)),
+	}, opts...)
+	grpcSrv := grpc.NewServer(opts...)
 
 	srv := new(Server)
 	testpb.RegisterTestServer(grpcSrv, srv)
@@ -0,0 +1,48 @@
+package internal
+
+import "reflect"
+
+// CycleCheckDepth is the depth of an error tree after which traversals check
+// for cycles, which can only be created by modifying errors after they're
+// wrapped. Trees are rarely this deep, so traversals of most errors don't pay
+// for the checks.
+const CycleCheckDepth = 100
+
+// OnPath returns true if err is one of the errors in path. Errors are
+// compared with ==, skipping errors of types which aren't comparable.
+func OnPath(path []error, err error) bool {
+	t := reflect.TypeOf(err)
+	if !t.Comparable() {
+		return false
+	}
+	for _, e := range path {
+		if reflect.TypeOf(e) == t && e == err {
+			return true
+		}
+	}
+	return false
+}
+
+// Path is the path from the root of an error tree to the error being visited
+// by a depth first traversal. It's passed by value, so that it's restored
+// when the traversal backtracks. Only the errors deeper than CycleCheckDepth
+// are kept.
+type Path struct {
+	depth int
+	deep  []error
+}
+
+// Visit returns the path extended with err, and false if err is already on
+// the path, i.e. the tree has a cycle and the traversal must not descend
+// into err.
+func (p Path) Visit(err error) (Path, bool) {
+	p.depth++
+	if p.depth <= CycleCheckDepth {
+		return p, true
+	}
+	if OnPath(p.deep, err) {
+		return p, false
+	}
+	p.deep = append(p.deep, err)
+	return p, true
+}
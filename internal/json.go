@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/peterlabuschagne/jettison/models"
+)
+
+// jsonError is the JSON representation of an Error and the errors it wraps.
+type jsonError struct {
+	Message    string            `json:"message,omitempty"`
+	Binary     string            `json:"binary,omitempty"`
+	StackTrace []string          `json:"stack_trace,omitempty"`
+	Code       string            `json:"code,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	KV         []models.KeyValue `json:"kv,omitempty"`
+	Retryable  bool              `json:"retryable,omitempty"`
+	HTTPStatus int               `json:"http_status,omitempty"`
+	ExitCode   int               `json:"exit_code,omitempty"`
+	Severity   string            `json:"severity,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	Timestamp  *time.Time        `json:"timestamp,omitempty"`
+
+	Wrapped *jsonError   `json:"wrapped,omitempty"`
+	Joined  []*jsonError `json:"joined,omitempty"`
+}
+
+// MarshalJSON satisfies the json.Marshaler interface, serialising the error
+// and every error in its tree.
+//
+// Non-jettison errors in the tree can't be reconstructed so only their message
+// is kept, this means that the unmarshalled error will no longer match them
+// using Is or As.
+func (je *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(errorToJSON(je, Path{}))
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface, reconstructing an
+// error serialised by MarshalJSON.
+func (je *Error) UnmarshalJSON(b []byte) error {
+	var j jsonError
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	switch err := errorFromJSON(&j).(type) {
+	case *Error:
+		*je = *err
+	default:
+		*je = Error{Err: err}
+	}
+	return nil
+}
+
+// errorToJSON converts err and the errors it wraps, stopping at errors
+// already on the path to err if the tree has a cycle.
+func errorToJSON(err error, path Path) *jsonError {
+	if err == nil {
+		return nil
+	}
+	var ok bool
+	if path, ok = path.Visit(err); !ok {
+		return nil
+	}
+	var j jsonError
+	switch unw := err.(type) {
+	case *Error:
+		j.Message = unw.Message
+		j.Binary = unw.Binary
+		j.StackTrace = unw.StackTrace
+		j.Code = unw.Code
+		j.Source = unw.Source
+		j.KV = unw.KV
+		j.Retryable = unw.Retryable
+		j.HTTPStatus = unw.HTTPStatus
+		j.ExitCode = unw.ExitCode
+		j.Severity = unw.Severity
+		j.Tags = unw.Tags
+		if !unw.Timestamp.IsZero() {
+			ts := unw.Timestamp
+			j.Timestamp = &ts
+		}
+		j.Wrapped = errorToJSON(unw.Err, path)
+	case interface{ Unwrap() []error }:
+		// The message of joined errors is made up of the joined messages
+		for _, e := range unw.Unwrap() {
+			if je := errorToJSON(e, path); je != nil {
+				j.Joined = append(j.Joined, je)
+			}
+		}
+	case interface{ Unwrap() error }:
+		// Only the wrapper's own message is kept, the wrapped error's message
+		// is added back by the reconstructed error
+		j.Message = err.Error()
+		if inner := unw.Unwrap(); inner != nil {
+			j.Message = strings.TrimSuffix(strings.TrimSuffix(j.Message, inner.Error()), ": ")
+			j.Wrapped = errorToJSON(inner, path)
+		}
+	default:
+		j.Message = err.Error()
+	}
+	return &j
+}
+
+func errorFromJSON(j *jsonError) error {
+	if len(j.Joined) > 0 {
+		var errs []error
+		for _, joinErr := range j.Joined {
+			errs = append(errs, errorFromJSON(joinErr))
+		}
+		return errors.Join(errs...)
+	}
+	je := &Error{
+		Message:    j.Message,
+		Binary:     j.Binary,
+		StackTrace: j.StackTrace,
+		Code:       j.Code,
+		Source:     j.Source,
+		KV:         j.KV,
+		Retryable:  j.Retryable,
+		HTTPStatus: j.HTTPStatus,
+		ExitCode:   j.ExitCode,
+		Severity:   j.Severity,
+		Tags:       j.Tags,
+	}
+	if j.Timestamp != nil {
+		je.Timestamp = *j.Timestamp
+	}
+	if j.Wrapped != nil {
+		je.Err = errorFromJSON(j.Wrapped)
+	}
+	return je
+}
+
+var (
+	_ json.Marshaler   = (*Error)(nil)
+	_ json.Unmarshaler = (*Error)(nil)
+)
@@ -0,0 +1,136 @@
+package internal_test
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/peterlabuschagne/jettison/errors"
+	"github.com/peterlabuschagne/jettison/internal"
+	"github.com/peterlabuschagne/jettison/models"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  *internal.Error
+	}{
+		{
+			name: "empty stack trace",
+			err:  &internal.Error{Message: "root error", Code: "root"},
+		},
+		{
+			name: "multi-hop",
+			err: &internal.Error{
+				Message:    "outer",
+				Code:       "outer",
+				HTTPStatus: 404,
+				Severity:   "warn",
+				Tags:       []string{"external"},
+				Timestamp:  time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC),
+				Source:     "outer.go:1",
+				KV:         []models.KeyValue{{Key: "a", Value: "1"}},
+				Err: &internal.Error{
+					Message:    "inner",
+					Code:       "inner",
+					Binary:     "service",
+					Retryable:  true,
+					ExitCode:   2,
+					StackTrace: []string{"frame one", "frame two"},
+					KV:         []models.KeyValue{{Key: "b", Value: "2"}},
+				},
+			},
+		},
+		{
+			name: "joined",
+			err: &internal.Error{
+				Message: "joined",
+				Err: stderrors.Join(
+					&internal.Error{Message: "one", Code: "one"},
+					&internal.Error{Message: "two", KV: []models.KeyValue{{Key: "c", Value: "3"}}},
+				),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := json.Marshal(tc.err)
+			require.NoError(t, err)
+
+			var act internal.Error
+			err = json.Unmarshal(b, &act)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.err.Error(), act.Error())
+			assert.Equal(t, errors.GetCodes(tc.err), errors.GetCodes(&act))
+			assert.Equal(t, errors.GetKeyValueList(tc.err), errors.GetKeyValueList(&act))
+			_, expTrace, _ := errors.GetLastStackTrace(tc.err)
+			_, actTrace, _ := errors.GetLastStackTrace(&act)
+			assert.Equal(t, expTrace, actTrace)
+			assert.Equal(t, errors.IsRetryable(tc.err), errors.IsRetryable(&act))
+			expStatus, expOK := errors.HTTPStatus(tc.err)
+			actStatus, actOK := errors.HTTPStatus(&act)
+			assert.Equal(t, expStatus, actStatus)
+			assert.Equal(t, expOK, actOK)
+			expSeverity, _ := errors.Severity(tc.err)
+			actSeverity, _ := errors.Severity(&act)
+			assert.Equal(t, expSeverity, actSeverity)
+			assert.Equal(t, errors.GetTags(tc.err), errors.GetTags(&act))
+			expExit, _ := errors.ExitCode(tc.err)
+			actExit, _ := errors.ExitCode(&act)
+			assert.Equal(t, expExit, actExit)
+			assert.Equal(t, errors.GetTimestamps(tc.err), errors.GetTimestamps(&act))
+		})
+	}
+}
+
+func TestJSONNonJettisonError(t *testing.T) {
+	err := &internal.Error{Message: "wrap", Err: io.EOF}
+	b, jerr := json.Marshal(err)
+	require.NoError(t, jerr)
+	assert.Equal(t, `{"message":"wrap","wrapped":{"message":"EOF"}}`, string(b))
+
+	var act internal.Error
+	require.NoError(t, json.Unmarshal(b, &act))
+	assert.False(t, errors.Is(&act, io.EOF))
+}
+
+func TestJSONStdlibWrapper(t *testing.T) {
+	inner := errors.New("ctx", errors.WithCode("ctx"))
+	err := errors.Wrap(fmt.Errorf("query: %w", inner), "outer")
+	b, jerr := json.Marshal(err)
+	require.NoError(t, jerr)
+
+	var act internal.Error
+	require.NoError(t, json.Unmarshal(b, &act))
+	assert.Equal(t, "outer: query: ctx", act.Error())
+	assert.Equal(t, err.Error(), act.Error())
+	assert.True(t, errors.IsCode(&act, "ctx"))
+}
+
+func TestJSONCycle(t *testing.T) {
+	// Modifying an error after wrapping it is the only way to create a cycle
+	a := &internal.Error{Message: "a"}
+	b := errors.Wrap(a, "b")
+	a.Err = b
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := json.Marshal(b)
+		assert.NoError(t, err)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("marshalling a cycle didn't terminate")
+	}
+}
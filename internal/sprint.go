@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// stringers maps types to the functions registered to format them with
+// RegisterStringer.
+var stringers sync.Map
+
+// RegisterStringer sets the function used by Sprint to format values of
+// type t, replacing any previously registered function.
+func RegisterStringer(t reflect.Type, fn func(any) string) {
+	stringers.Store(t, fn)
+}
+
+var nosprints = map[reflect.Kind]bool{
+	reflect.Struct:        true,
+	reflect.Map:           true,
+	reflect.Slice:         true,
+	reflect.Array:         true,
+	reflect.Ptr:           true,
+	reflect.UnsafePointer: true,
+	reflect.Uintptr:       true,
+	reflect.Func:          true,
+	reflect.Chan:          true,
+	reflect.Interface:     true,
+}
+
+// Sprint formats a key value's value. Values of types registered with
+// RegisterStringer are formatted by the registered function. Simple values
+// and fmt.Stringer or fmt.Formatter implementations are printed, but complex
+// values like slices, maps and structs are not since it is considered bad
+// practice.
+func Sprint(i interface{}) string {
+	if i == nil {
+		return "<nil>"
+	}
+	if fn, ok := stringers.Load(reflect.TypeOf(i)); ok {
+		return fn.(func(any) string)(i)
+	}
+
+	// Shortcut some simple types
+	switch i.(type) {
+	case bool:
+		return fmt.Sprint(i)
+	case int:
+		return fmt.Sprint(i)
+	case int64:
+		return fmt.Sprint(i)
+	case string:
+		return fmt.Sprint(i)
+	case fmt.Stringer:
+		return fmt.Sprint(i)
+	case fmt.Formatter:
+		return fmt.Sprint(i)
+	}
+	k := reflect.TypeOf(i).Kind()
+	if nosprints[k] {
+		return "<" + k.String() + ">"
+	}
+	return fmt.Sprint(i)
+}
@@ -1,10 +1,13 @@
 package internal
 
 import (
+	stderrors "errors"
 	"fmt"
 	"io"
+	"reflect"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"golang.org/x/xerrors"
 
@@ -20,17 +23,50 @@ type Error struct {
 	Code       string
 	Source     string
 	KV         []models.KeyValue
+	Retryable  bool
+	HTTPStatus int
+	ExitCode   int
+	Severity   string
+	Tags       []string
+	// Timestamp is when the error was created or wrapped
+	Timestamp time.Time
+	// Metadata are values which can be retrieved with As
+	Metadata []any
+
+	// origin is the error this one is a copy of, see Copy
+	origin *Error
 }
 
 // Format satisfies the fmt.Formatter interface providing customizable formatting:
 //
 //	%s, %v formats all wrapped error messages concatenated with ": ".
-//	%+v, %#v does the above but also adds error parameters; "(k1=v1, k2=v2)".
-func (je *Error) Format(state fmt.State, _ rune) {
-	withParams := state.Flag(int('#')) || state.Flag(int('+'))
-	p := &printer{Writer: state, detailed: withParams}
-	var next xerrors.Formatter = je
+//	%#v does the above but also adds error parameters; "(k1=v1, k2=v2)".
+//	%+v formats each wrapped error on its own line, followed by its code,
+//	    source, parameters and stack trace.
+func (je *Error) Format(state fmt.State, verb rune) {
+	if verb == 'v' && state.Flag(int('+')) {
+		je.formatDetailed(state)
+		return
+	}
+	withParams := state.Flag(int('#'))
+	writeChain(&printer{Writer: state, detailed: withParams}, je, Path{})
+}
+
+// joinType is the type of the standard library's joined errors.
+var joinType = reflect.TypeOf(stderrors.Join(io.EOF))
+
+// writeChain writes the messages of next and the errors it wraps, separated
+// by ": ". The standard library's joined errors are written like by their
+// Error method, but without calling it, so that the path is kept and
+// writeChain terminates even if the errors have a cycle.
+func writeChain(p *printer, next xerrors.Formatter, path Path) {
 	for {
+		if err, isErr := next.(error); isErr {
+			var ok bool
+			if path, ok = path.Visit(err); !ok {
+				return
+			}
+		}
 		pre := p.written
 		res := next.FormatError(p)
 		if res == nil {
@@ -41,13 +77,85 @@ func (je *Error) Format(state fmt.State, _ rune) {
 		}
 		formatter, ok := res.(xerrors.Formatter)
 		if !ok {
-			_, _ = p.Write([]byte(res.Error()))
+			writeError(p, res, path)
 			return
 		}
 		next = formatter
 	}
 }
 
+// writeError writes err like its Error method, see writeChain.
+func writeError(p *printer, err error, path Path) {
+	if je, ok := err.(*Error); ok {
+		// Error formats without the parameters
+		writeChain(&printer{Writer: p.Writer}, je, path)
+		return
+	}
+	if reflect.TypeOf(err) != joinType {
+		_, _ = p.Write([]byte(err.Error()))
+		return
+	}
+	var ok bool
+	if path, ok = path.Visit(err); !ok {
+		return
+	}
+	for i, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		if i > 0 {
+			_, _ = p.Write([]byte("\n"))
+		}
+		writeError(p, e, path)
+	}
+}
+
+// formatDetailed writes a multi-line description of each error in the chain.
+// Non-jettison errors are formatted with %+v and end the chain. Empty
+// messages, like those of joined errors, are skipped.
+func (je *Error) formatDetailed(w io.Writer) {
+	var (
+		err     error = je
+		path    Path
+		written bool
+	)
+	// line writes a line, preceded by a new line unless it's the first
+	line := func(format string, args ...any) {
+		if written {
+			_, _ = io.WriteString(w, "\n")
+		}
+		_, _ = fmt.Fprintf(w, format, args...)
+		written = true
+	}
+	for err != nil {
+		var ok bool
+		if path, ok = path.Visit(err); !ok {
+			return
+		}
+		e, ok := err.(*Error)
+		if !ok {
+			line("%+v", err)
+			return
+		}
+		if e.Message != "" {
+			line("%s", e.Message)
+		}
+		if e.Code != "" {
+			line("  code: %s", e.Code)
+		}
+		if e.Source != "" {
+			line("  source: %s", e.Source)
+		}
+		for _, kv := range models.ResolveAll(e.KV) {
+			line("  %s=%s", kv.Key, kv.Value)
+		}
+		if len(e.StackTrace) > 0 {
+			line("  stacktrace (%s):", e.Binary)
+			for _, l := range e.StackTrace {
+				line("    %s", l)
+			}
+		}
+		err = e.Err
+	}
+}
+
 // FormatError implements the Formatter interface for optionally detailed
 // error message rendering - see the Go 2 error printing draft proposal for
 // details.
@@ -56,7 +164,7 @@ func (je *Error) FormatError(p xerrors.Printer) error {
 	args := []interface{}{je.Message}
 	if p.Detail() && len(je.KV) > 0 {
 		var fmts []string
-		for _, kv := range je.KV {
+		for _, kv := range models.ResolveAll(je.KV) {
 			fmts = append(fmts, "%s")
 			args = append(args, kv.Key+"="+kv.Value)
 		}
@@ -68,6 +176,37 @@ func (je *Error) FormatError(p xerrors.Printer) error {
 	return je.Err
 }
 
+// Copy returns a shallow copy of the error, which still matches it with Is,
+// e.g. so that a sentinel error can be changed without wrapping it.
+func (je *Error) Copy() *Error {
+	c := *je
+	c.origin = je
+	return &c
+}
+
+// Clone returns a copy of the error which can be modified without
+// affecting the original. The wrapped error is shared with the original.
+func (je *Error) Clone() *Error {
+	c := *je
+	if len(je.StackTrace) > 0 {
+		c.StackTrace = make([]string, len(je.StackTrace))
+		copy(c.StackTrace, je.StackTrace)
+	}
+	if len(je.KV) > 0 {
+		c.KV = make([]models.KeyValue, len(je.KV))
+		copy(c.KV, je.KV)
+	}
+	if len(je.Tags) > 0 {
+		c.Tags = make([]string, len(je.Tags))
+		copy(c.Tags, je.Tags)
+	}
+	if len(je.Metadata) > 0 {
+		c.Metadata = make([]any, len(je.Metadata))
+		copy(c.Metadata, je.Metadata)
+	}
+	return &c
+}
+
 func (je *Error) Error() string {
 	return fmt.Sprintf("%v", je)
 }
@@ -80,8 +219,63 @@ func (je *Error) Unwrap() error {
 	return je.Err
 }
 
+// Temporary returns true if any jettison error in the chain is retryable,
+// for compatibility with code checking for net.Error's Temporary method.
+func (je *Error) Temporary() bool {
+	return isRetryable(je, Path{})
+}
+
+func isRetryable(err error, path Path) bool {
+	if err == nil {
+		return false
+	}
+	var ok bool
+	if path, ok = path.Visit(err); !ok {
+		return false
+	}
+	switch unw := err.(type) {
+	case *Error:
+		return unw.Retryable || isRetryable(unw.Err, path)
+	case interface{ Unwrap() error }:
+		return isRetryable(unw.Unwrap(), path)
+	case interface{ Unwrap() []error }:
+		for _, e := range unw.Unwrap() {
+			if isRetryable(e, path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// As sets target to the last of the error's metadata which can be assigned
+// to it. Wrapped errors aren't checked, the errors package's As does that.
+func (je *Error) As(target any) bool {
+	val := reflect.ValueOf(target)
+	if len(je.Metadata) == 0 || val.Kind() != reflect.Pointer || val.IsNil() {
+		return false
+	}
+	typ := val.Type().Elem()
+	for i := len(je.Metadata) - 1; i >= 0; i-- {
+		m := je.Metadata[i]
+		if m != nil && reflect.TypeOf(m).AssignableTo(typ) {
+			val.Elem().Set(reflect.ValueOf(m))
+			return true
+		}
+	}
+	return false
+}
+
+// Code is an error which matches any jettison error with the same code.
+type Code string
+
+func (c Code) Error() string {
+	return string(c)
+}
+
 // Is returns true if the errors are equal as values, or the target is also
-// a jettison error and contains the same code as the target.
+// a jettison error and contains the same code as the target, or the target is
+// a Code equal to the error's code.
 func (je *Error) Is(target error) bool {
 	if je == nil {
 		return target == nil
@@ -89,6 +283,14 @@ func (je *Error) Is(target error) bool {
 	if je == target {
 		return true
 	}
+	for o := je.origin; o != nil; o = o.origin {
+		if o == target {
+			return true
+		}
+	}
+	if c, ok := target.(Code); ok {
+		return je.Code != "" && je.Code == string(c)
+	}
 	targetJErr, ok := target.(*Error)
 	if !ok {
 		return false
@@ -62,6 +62,40 @@ func TestError(t *testing.T) {
 	}
 }
 
+func TestClone(t *testing.T) {
+	wrapped := io.EOF
+	je := &internal.Error{
+		Message:    "msg",
+		Err:        wrapped,
+		StackTrace: make([]string, 1, 4),
+		KV:         make([]models.KeyValue, 1, 4),
+		Tags:       make([]string, 1, 4),
+		Metadata:   make([]any, 1, 4),
+	}
+	je.StackTrace[0] = "trace"
+	je.KV[0] = models.KeyValue{Key: "k", Value: "v"}
+	je.Tags[0] = "tag"
+	je.Metadata[0] = 1
+
+	c := je.Clone()
+	assert.Equal(t, je, c)
+	c.StackTrace[0] = "changed"
+	c.KV[0].Value = "changed"
+	c.Tags[0] = "changed"
+	c.Metadata[0] = 2
+	c.KV = append(c.KV, models.KeyValue{Key: "k2"})
+	c.Tags = append(c.Tags, "tag2")
+
+	assert.Equal(t, []string{"trace"}, je.StackTrace)
+	assert.Equal(t, []models.KeyValue{{Key: "k", Value: "v"}}, je.KV)
+	assert.Equal(t, models.KeyValue{}, je.KV[:2][1])
+	assert.Equal(t, []string{"tag"}, je.Tags)
+	assert.Equal(t, "", je.Tags[:2][1])
+	assert.Equal(t, []any{1}, je.Metadata)
+	// The wrapped error is shared
+	assert.Equal(t, wrapped, c.Err)
+}
+
 func TestFormat(t *testing.T) {
 	testCases := []struct {
 		name       string
@@ -108,6 +142,79 @@ func TestFormat(t *testing.T) {
 	}
 }
 
+func TestFormatDetailed(t *testing.T) {
+	testCases := []struct {
+		name        string
+		err         *internal.Error
+		expDetailed string
+	}{
+		{
+			name:        "message only",
+			err:         &internal.Error{Message: "root error"},
+			expDetailed: "root error",
+		},
+		{
+			name: "wrapped",
+			err: &internal.Error{
+				Message: "wrap one",
+				Code:    "wrap_code",
+				KV:      []models.KeyValue{{Key: "w", Value: "w1"}},
+				Err: &internal.Error{
+					Message:    "root error",
+					Source:     "type_test.go:1",
+					Binary:     "service",
+					StackTrace: []string{"frame one", "frame two"},
+					KV: []models.KeyValue{
+						{Key: "p1", Value: "v1"},
+						{Key: "p2", Value: "v2"},
+					},
+				},
+			},
+			expDetailed: "wrap one\n" +
+				"  code: wrap_code\n" +
+				"  w=w1\n" +
+				"root error\n" +
+				"  source: type_test.go:1\n" +
+				"  p1=v1\n" +
+				"  p2=v2\n" +
+				"  stacktrace (service):\n" +
+				"    frame one\n" +
+				"    frame two",
+		},
+		{
+			name:        "sql error",
+			err:         &internal.Error{Message: "wrap sql error", Err: sql.ErrNoRows},
+			expDetailed: "wrap sql error\nsql: no rows in result set",
+		},
+		{
+			name: "joined",
+			err: &internal.Error{
+				Message: "wrap join",
+				Err: &internal.Error{
+					Source: "join.go:1",
+					Err:    errors.Join(io.EOF, sql.ErrNoRows),
+				},
+			},
+			expDetailed: "wrap join\n" +
+				"  source: join.go:1\n" +
+				"EOF\n" +
+				"sql: no rows in result set",
+		},
+		{
+			name:        "empty message",
+			err:         &internal.Error{Code: "empty"},
+			expDetailed: "  code: empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expDetailed, fmt.Sprintf("%+v", tc.err))
+			assert.Equal(t, tc.err.Error(), fmt.Sprintf("%s", tc.err))
+		})
+	}
+}
+
 func TestLegacyCallback(t *testing.T) {
 	testCases := []struct {
 		name    string
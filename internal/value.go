@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/peterlabuschagne/jettison/models"
+)
+
+var maxValueLength atomic.Int64
+
+// SetMaxValueLength sets the maximum length in bytes of key/value values,
+// see TruncateValue. n <= 0 means no limit.
+func SetMaxValueLength(n int) {
+	maxValueLength.Store(int64(n))
+}
+
+const (
+	truncatedPrefix = "...[truncated "
+	truncatedSuffix = " bytes]"
+)
+
+// TruncateValue returns s truncated to the length set by SetMaxValueLength,
+// with the number of bytes removed appended. Multi-byte characters are never
+// split, so the value may be truncated to slightly less than the limit.
+// Values which have already been truncated are returned unchanged, e.g. the
+// values of errors when they're logged.
+func TruncateValue(s string) string {
+	max := int(maxValueLength.Load())
+	if max <= 0 || len(s) <= max || isTruncated(s, max) {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + truncatedPrefix + strconv.Itoa(len(s)-cut) + truncatedSuffix
+}
+
+// isTruncated returns true if s was returned by TruncateValue with a limit
+// of at most max.
+func isTruncated(s string, max int) bool {
+	if !strings.HasSuffix(s, truncatedSuffix) {
+		return false
+	}
+	i := strings.LastIndex(s, truncatedPrefix)
+	if i < 0 || i > max {
+		return false
+	}
+	_, err := strconv.Atoi(s[i+len(truncatedPrefix) : len(s)-len(truncatedSuffix)])
+	return err == nil
+}
+
+// TruncateValues returns kvs with their values truncated by TruncateValue.
+// kvs is returned if no values are truncated, otherwise a copy is returned.
+func TruncateValues(kvs []models.KeyValue) []models.KeyValue {
+	var ret []models.KeyValue
+	for i, kv := range kvs {
+		v := TruncateValue(kv.Value)
+		if v == kv.Value {
+			continue
+		}
+		if ret == nil {
+			ret = make([]models.KeyValue, len(kvs))
+			copy(ret, kvs)
+		}
+		ret[i].Value = v
+		// A truncated value is no longer a number or boolean
+		ret[i].Type = models.TypeString
+	}
+	if ret == nil {
+		return kvs
+	}
+	return ret
+}
@@ -0,0 +1,58 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/peterlabuschagne/jettison/internal"
+	"github.com/peterlabuschagne/jettison/models"
+)
+
+func TestTruncateValue(t *testing.T) {
+	testCases := []struct {
+		name     string
+		max      int
+		value    string
+		expValue string
+	}{
+		{name: "no limit", value: "hello", expValue: "hello"},
+		{name: "within limit", max: 5, value: "hello", expValue: "hello"},
+		{name: "truncated", max: 4, value: "hello", expValue: "hell...[truncated 1 bytes]"},
+		// "é" is two bytes, so it can't be split at the fourth byte
+		{name: "multi-byte", max: 4, value: "caféé", expValue: "caf...[truncated 4 bytes]"},
+		{name: "multi-byte boundary", max: 5, value: "caféé", expValue: "café...[truncated 2 bytes]"},
+		{name: "already truncated", max: 4, value: "hell...[truncated 1 bytes]", expValue: "hell...[truncated 1 bytes]"},
+		{
+			name:     "truncated with higher limit",
+			max:      2,
+			value:    "hell...[truncated 1 bytes]",
+			expValue: "he...[truncated 24 bytes]",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			internal.SetMaxValueLength(tc.max)
+			t.Cleanup(func() { internal.SetMaxValueLength(0) })
+
+			assert.Equal(t, tc.expValue, internal.TruncateValue(tc.value))
+		})
+	}
+}
+
+func TestTruncateValues(t *testing.T) {
+	internal.SetMaxValueLength(2)
+	t.Cleanup(func() { internal.SetMaxValueLength(0) })
+
+	kvs := []models.KeyValue{{Key: "a", Value: "1"}, {Key: "b", Value: "123", Type: models.TypeInt}}
+	assert.Equal(t, []models.KeyValue{
+		{Key: "a", Value: "1"},
+		{Key: "b", Value: "12...[truncated 1 bytes]"},
+	}, internal.TruncateValues(kvs))
+	// The original slice is not modified
+	assert.Equal(t, "123", kvs[1].Value)
+
+	short := []models.KeyValue{{Key: "a", Value: "1"}}
+	assert.Equal(t, &short[0], &internal.TruncateValues(short)[0])
+}
@@ -3,10 +3,11 @@
 package j
 
 import (
-	"fmt"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/peterlabuschagne/jettison/errors"
 	"github.com/peterlabuschagne/jettison/internal"
@@ -44,18 +45,20 @@ func KS(key string, value string) MKV {
 // like slices, maps, structs are not printed since it is considered
 // bad practice.
 //
+// The key value pairs are always added in the order of their normalised
+// keys, regardless of map iteration order. Keys which normalise to the same
+// key are added in the order of the original keys.
+//
 //	Usage:
 //	  log.InfoCtx(ctx, "msg", j.MKV{"k1": 1, "k2": "v"})
 type MKV map[string]any
 
 func (m MKV) ContextKeys() []models.KeyValue {
 	res := make([]models.KeyValue, 0, len(m))
-	for k, v := range m {
-		res = append(res, models.KeyValue{Key: normalise(k), Value: sprint(v)})
+	for _, k := range sortedKeys(m) {
+		res = append(res, models.KeyValue{Key: normalise(k), Value: internal.Sprint(m[k])})
 	}
-	sort.Slice(res, func(i, j int) bool {
-		return res[i].Key < res[j].Key
-	})
+	sortKeyValues(res)
 	return res
 }
 
@@ -67,7 +70,8 @@ func (m MKV) ApplyToError(je *internal.Error) {
 	je.KV = append(je.KV, m.ContextKeys()...)
 }
 
-// MKS is a multi jettison key value string option.
+// MKS is a multi jettison key value string option. The key value pairs are
+// added in the same order as MKV.
 //
 //	Usage:
 //	  log.InfoCtx(ctx, "msg", j.MKS{"k1": "v1", "k2": "v2"})
@@ -75,12 +79,10 @@ type MKS map[string]string
 
 func (m MKS) ContextKeys() []models.KeyValue {
 	res := make([]models.KeyValue, 0, len(m))
-	for k, v := range m {
-		res = append(res, models.KeyValue{Key: normalise(k), Value: v})
+	for _, k := range sortedKeys(m) {
+		res = append(res, models.KeyValue{Key: normalise(k), Value: m[k]})
 	}
-	sort.Slice(res, func(i, j int) bool {
-		return res[i].Key < res[j].Key
-	})
+	sortKeyValues(res)
 	return res
 }
 
@@ -92,51 +94,150 @@ func (m MKS) ApplyToError(je *internal.Error) {
 	je.KV = append(je.KV, m.ContextKeys()...)
 }
 
-// C is an alias for jettison/errors.WithCode. Since this
-// should only be used with sentinel errors it also clears the useless
-// init-time stack trace allowing wrapping to add proper stack trace.
-func C(code string) errors.Option {
-	return errors.C(code)
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
-var nosprints = map[reflect.Kind]bool{
-	reflect.Struct:        true,
-	reflect.Map:           true,
-	reflect.Slice:         true,
-	reflect.Array:         true,
-	reflect.Ptr:           true,
-	reflect.UnsafePointer: true,
-	reflect.Uintptr:       true,
-	reflect.Func:          true,
-	reflect.Chan:          true,
-	reflect.Interface:     true,
+// sortKeyValues sorts kvs by key, keeping the order of equal keys.
+func sortKeyValues(kvs []models.KeyValue) {
+	sort.SliceStable(kvs, func(i, j int) bool {
+		return kvs[i].Key < kvs[j].Key
+	})
 }
 
-func sprint(i interface{}) string {
-	if i == nil {
-		return "<nil>"
-	}
+// TypedKV is a jettison key value option which keeps the type of its value,
+// so that JSON formatted logs contain numbers and booleans rather than
+// strings. Text formatted logs are the same as for KV.
+type TypedKV models.KeyValue
 
-	// Shortcut some simple types
-	switch i.(type) {
-	case bool:
-		return fmt.Sprint(i)
-	case int:
-		return fmt.Sprint(i)
-	case int64:
-		return fmt.Sprint(i)
-	case string:
-		return fmt.Sprint(i)
-	case fmt.Stringer:
-		return fmt.Sprint(i)
-	case fmt.Formatter:
-		return fmt.Sprint(i)
-	}
-	k := reflect.TypeOf(i).Kind()
-	if nosprints[k] {
-		return "<" + k.String() + ">"
+// Int returns a jettison key value option with an integer value.
+func Int(key string, value int) TypedKV {
+	return Int64(key, int64(value))
+}
+
+// Int64 returns a jettison key value option with an integer value.
+func Int64(key string, value int64) TypedKV {
+	return TypedKV{Key: normalise(key), Value: strconv.FormatInt(value, 10), Type: models.TypeInt}
+}
+
+// Float64 returns a jettison key value option with a floating point value,
+// formatted like KV. NaN and infinite values are written as JSON strings.
+func Float64(key string, value float64) TypedKV {
+	return TypedKV{Key: normalise(key), Value: strconv.FormatFloat(value, 'g', -1, 64), Type: models.TypeFloat}
+}
+
+// Bool returns a jettison key value option with a boolean value.
+func Bool(key string, value bool) TypedKV {
+	return TypedKV{Key: normalise(key), Value: strconv.FormatBool(value), Type: models.TypeBool}
+}
+
+// Time returns a jettison key value option with the time formatted using
+// time.RFC3339Nano.
+func Time(key string, value time.Time) TypedKV {
+	return TypedKV{Key: normalise(key), Value: value.Format(time.RFC3339Nano)}
+}
+
+// Duration returns a jettison key value option with the duration formatted
+// by time.Duration.String, e.g. "1.5s".
+func Duration(key string, value time.Duration) TypedKV {
+	return TypedKV{Key: normalise(key), Value: value.String()}
+}
+
+func (kv TypedKV) ContextKeys() []models.KeyValue {
+	return []models.KeyValue{models.KeyValue(kv)}
+}
+
+func (kv TypedKV) ApplyToLog(l *log.Entry) {
+	l.Parameters = append(l.Parameters, models.KeyValue(kv))
+}
+
+func (kv TypedKV) ApplyToError(je *internal.Error) {
+	je.KV = append(je.KV, models.KeyValue(kv))
+}
+
+// KVFunc returns a jettison key value option whose value is computed by fn
+// when it's logged or sent over gRPC, rather than when the option is used.
+// This avoids computing expensive values for errors which may never be
+// logged. fn is called at most once per option, from any goroutine.
+//
+//	Usage:
+//	  errors.Wrap(err, "msg", j.KVFunc("request", func() string { return dump(req) }))
+func KVFunc(key string, fn func() string) TypedKV {
+	return TypedKV(models.Lazy(normalise(key), fn))
+}
+
+// RegisterStringer sets the function used to format values of type t in
+// KV, MKV and log.WithField, e.g. so that a domain type is always formatted
+// in the same way. It replaces any function previously registered for t and
+// takes precedence over fmt.Stringer implementations. It's safe to call
+// concurrently with logging, though it's normally called from init.
+//
+//	Usage:
+//	  j.RegisterStringer(reflect.TypeOf(AccountID(0)), func(v any) string {
+//	    return "acc_" + strconv.Itoa(int(v.(AccountID)))
+//	  })
+func RegisterStringer(t reflect.Type, fn func(any) string) {
+	internal.RegisterStringer(t, fn)
+}
+
+// GroupDelimiter separates the prefix of a Group from the keys it contains.
+const GroupDelimiter = "."
+
+// Group returns a jettison key value option with the keys of the given
+// options prefixed by prefix and GroupDelimiter, i.e. "prefix.key".
+// Groups can be nested, in which case the prefixes are applied cumulatively.
+// If several options have the same key, the last value is used. The key
+// values keep their types, and the values of KVFunc options are still only
+// computed when they're logged or sent over gRPC.
+//
+//	Usage:
+//	  log.Info(ctx, "msg", j.Group("db", j.KV("id", 1), j.Group("conn", j.KV("id", 2))))
+//	  // db.id=1 db.conn.id=2
+func Group(prefix string, kvs ...log.ContextOption) KVGroup {
+	index := make(map[string]int)
+	var res KVGroup
+	for _, o := range kvs {
+		for _, kv := range o.ContextKeys() {
+			kv.Key = normalise(prefix + GroupDelimiter + kv.Key)
+			if i, ok := index[kv.Key]; ok {
+				res[i] = kv
+				continue
+			}
+			index[kv.Key] = len(res)
+			res = append(res, kv)
+		}
 	}
-	return fmt.Sprint(i)
+	sortKeyValues(res)
+	return res
+}
+
+// KVGroup is the jettison key value option returned by Group.
+type KVGroup []models.KeyValue
+
+func (g KVGroup) ContextKeys() []models.KeyValue {
+	res := make([]models.KeyValue, len(g))
+	copy(res, g)
+	return res
+}
+
+func (g KVGroup) ApplyToLog(l *log.Entry) {
+	l.Parameters = append(l.Parameters, g...)
+}
+
+func (g KVGroup) ApplyToError(je *internal.Error) {
+	je.KV = append(je.KV, g...)
+}
+
+// C is an alias for jettison/errors.WithCode. Since this
+// should only be used with sentinel errors it also clears the useless
+// init-time stack trace allowing wrapping to add proper stack trace.
+func C(code string) errors.Option {
+	return errors.C(code)
 }
 
 // normalise modifies the given key to conform to gRPC metadata requirements,
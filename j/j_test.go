@@ -1,7 +1,10 @@
 package j
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
 	"sync"
 	"testing"
@@ -12,6 +15,8 @@ import (
 
 	"github.com/peterlabuschagne/jettison/errors"
 	"github.com/peterlabuschagne/jettison/internal"
+	"github.com/peterlabuschagne/jettison/log"
+	"github.com/peterlabuschagne/jettison/models"
 )
 
 // fmtonly tests sprint if fmt.Formatter but not fmt.Stringer.
@@ -24,7 +29,7 @@ func (_ fmtonly) Format(s fmt.State, c rune) {
 func TestSprint(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
-			require.Equal(t, test.Output, sprint(test.Input))
+			require.Equal(t, test.Output, internal.Sprint(test.Input))
 		})
 	}
 }
@@ -33,7 +38,7 @@ func TestSprint(t *testing.T) {
 func BenchmarkAll(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		for _, test := range tests {
-			sprint(test.Input)
+			internal.Sprint(test.Input)
 		}
 	}
 }
@@ -51,7 +56,7 @@ func BenchmarkAllOld(b *testing.B) {
 func BenchmarkSimple(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		for _, input := range simple {
-			sprint(input)
+			internal.Sprint(input)
 		}
 	}
 }
@@ -70,7 +75,7 @@ func BenchmarkFull(b *testing.B) {
 	for _, t := range tests {
 		b.Run(t.Name, func(b *testing.B) {
 			for i := 0; i < b.N; i++ {
-				_ = sprint(t.Input)
+				_ = internal.Sprint(t.Input)
 			}
 		})
 		b.Run(t.Name+"Fmt", func(b *testing.B) {
@@ -197,3 +202,176 @@ func TestNormalise(t *testing.T) {
 		})
 	}
 }
+
+type loggerFunc func(log.Entry)
+
+func (f loggerFunc) Log(_ context.Context, e log.Entry) string {
+	f(e)
+	return ""
+}
+
+func TestGroup(t *testing.T) {
+	var entry log.Entry
+	log.SetLoggerForTesting(t, loggerFunc(func(e log.Entry) {
+		entry = e
+	}))
+
+	log.Info(context.Background(), "msg",
+		Group("db", KV("id", 1), Group("Conn", MKS{"id": "2", "host": "a"})),
+		Group("cache", KS("id", "3")),
+		KV("id", 4),
+	)
+	assert.Equal(t, []models.KeyValue{
+		{Key: "cache.id", Value: "3"},
+		{Key: "db.conn.host", Value: "a"},
+		{Key: "db.conn.id", Value: "2"},
+		{Key: "db.id", Value: "1"},
+		{Key: "id", Value: "4"},
+	}, entry.Parameters)
+
+	err := errors.New("err", Group("db", KV("id", 1)))
+	assert.Equal(t, map[string]string{"db.id": "1"}, errors.GetKeyValues(err))
+}
+
+func TestGroupLazy(t *testing.T) {
+	var entry log.Entry
+	log.SetLoggerForTesting(t, loggerFunc(func(e log.Entry) {
+		entry = e
+	}))
+
+	var calls int
+	err := errors.New("err", Group("req", KVFunc("dump", func() string {
+		calls++
+		return "dumped"
+	}), Int("n", 2)))
+	assert.Equal(t, 0, calls)
+
+	log.Error(context.Background(), err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []models.KeyValue{
+		{Key: "req.dump", Value: "dumped"},
+		{Key: "req.n", Value: "2", Type: models.TypeInt},
+	}, entry.Parameters)
+}
+
+func TestTypedKV(t *testing.T) {
+	var entry log.Entry
+	log.SetLoggerForTesting(t, loggerFunc(func(e log.Entry) {
+		entry = e
+	}))
+
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC)
+	log.Info(context.Background(), "msg",
+		Int("int", -1),
+		Int64("int64", 64),
+		Float64("float", 1.5),
+		Float64("nan", math.NaN()),
+		Bool("bool", true),
+		Time("time", ts),
+		Duration("duration", 1500*time.Millisecond),
+	)
+
+	var text []string
+	for _, kv := range entry.Parameters {
+		text = append(text, kv.Key+"="+kv.Value)
+	}
+	assert.Equal(t, []string{
+		"bool=true",
+		"duration=1.5s",
+		"float=1.5",
+		"int=-1",
+		"int64=64",
+		"nan=NaN",
+		"time=2023-01-02T03:04:05.000000006Z",
+	}, text)
+
+	b, err := json.Marshal(entry.Parameters)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"key":"bool","value":true},
+		{"key":"duration","value":"1.5s"},
+		{"key":"float","value":1.5},
+		{"key":"int","value":-1},
+		{"key":"int64","value":64},
+		{"key":"nan","value":"NaN"},
+		{"key":"time","value":"2023-01-02T03:04:05.000000006Z"}
+	]`, string(b))
+
+	var act []models.KeyValue
+	require.NoError(t, json.Unmarshal(b, &act))
+	exp := entry.Parameters
+	exp[5].Type = models.TypeString // NaN isn't a JSON number
+	assert.Equal(t, exp, act)
+}
+
+func TestMKVOrder(t *testing.T) {
+	m := MKV{"b": 2, "a": 1, "ID": "upper", "id": "lower", "i d": "space", "c": true}
+	exp := []models.KeyValue{
+		{Key: "a", Value: "1"},
+		{Key: "b", Value: "2"},
+		{Key: "c", Value: "true"},
+		{Key: "id", Value: "upper"},
+		{Key: "id", Value: "space"},
+		{Key: "id", Value: "lower"},
+	}
+
+	// Map iteration order is random, so repeat to catch any dependence on it
+	for i := 0; i < 100; i++ {
+		require.Equal(t, exp, m.ContextKeys())
+		require.Equal(t, exp, errors.GetKeyValueList(errors.New("err", m)))
+		require.Equal(t, map[string]string{"a": "1", "b": "2", "c": "true", "id": "upper"},
+			errors.GetKeyValues(errors.New("err", m)))
+
+		ms := MKS{"b": "2", "a": "1", "ID": "upper", "id": "lower"}
+		require.Equal(t, []models.KeyValue{
+			{Key: "a", Value: "1"},
+			{Key: "b", Value: "2"},
+			{Key: "id", Value: "upper"},
+			{Key: "id", Value: "lower"},
+		}, ms.ContextKeys())
+	}
+}
+
+func TestKVFunc(t *testing.T) {
+	var entry log.Entry
+	log.SetLoggerForTesting(t, loggerFunc(func(e log.Entry) {
+		entry = e
+	}))
+
+	var calls int
+	err := errors.New("err", KVFunc("Lazy", func() string {
+		calls++
+		return "value"
+	}))
+	err = errors.Wrap(err, "wrapped")
+	assert.Equal(t, 0, calls)
+
+	ctx := context.Background()
+	log.Error(ctx, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []models.KeyValue{{Key: "lazy", Value: "value"}}, entry.Parameters)
+	require.NotNil(t, entry.ErrorObject)
+	assert.Equal(t, []models.KeyValue{{Key: "lazy", Value: "value"}}, entry.ErrorObject.Parameters)
+
+	log.Error(ctx, err)
+	assert.Equal(t, map[string]string{"lazy": "value"}, errors.GetKeyValues(err))
+	assert.Equal(t, 1, calls)
+}
+
+type accountID int
+
+func TestRegisterStringer(t *testing.T) {
+	RegisterStringer(reflect.TypeOf(accountID(0)), func(v any) string {
+		return fmt.Sprintf("acc_%d", v.(accountID))
+	})
+
+	assert.Equal(t, []models.KeyValue{{Key: "account", Value: "acc_5"}},
+		KV("account", accountID(5)).ContextKeys())
+
+	var entry log.Entry
+	log.SetLoggerForTesting(t, loggerFunc(func(e log.Entry) {
+		entry = e
+	}))
+	log.Info(context.Background(), "msg", log.WithField("account", accountID(6)))
+	assert.Equal(t, []models.KeyValue{{Key: "account", Value: "acc_6"}}, entry.Parameters)
+}
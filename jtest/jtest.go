@@ -132,12 +132,17 @@ func failJKeyValuesMismatch(key, expected, actual string, msgs ...interface{}) s
 }
 
 type prettyError struct {
-	Message string            `yaml:"message,omitempty"`
-	Code    string            `yaml:"code,omitempty"`
-	KV      []models.KeyValue `yaml:"kv,omitempty"`
+	Message string     `yaml:"message,omitempty"`
+	Code    string     `yaml:"code,omitempty"`
+	KV      []prettyKV `yaml:"kv,omitempty"`
 	// TODO(adam): Add source
 }
 
+type prettyKV struct {
+	Key   string `yaml:"key"`
+	Value string `yaml:"value"`
+}
+
 func pretty(err error) string {
 	if err == nil {
 		return fmt.Sprint(err)
@@ -162,7 +167,9 @@ func pretty(err error) string {
 			if je.Code != "" {
 				pret.Code = je.Code
 			}
-			pret.KV = append(pret.KV, je.KV...)
+			for _, kv := range models.ResolveAll(je.KV) {
+				pret.KV = append(pret.KV, prettyKV{Key: kv.Key, Value: kv.Value})
+			}
 		}
 		pretties = append(pretties, pret)
 	}
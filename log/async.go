@@ -0,0 +1,176 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/peterlabuschagne/jettison/errors"
+)
+
+// OverflowPolicy is what an AsyncLogger does with logs when its buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for space in the buffer, so no logs are lost.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop discards the log, see AsyncLogger.Dropped.
+	OverflowDrop
+)
+
+// AsyncConfig configures an AsyncLogger.
+type AsyncConfig struct {
+	// BufferSize is the number of logs which can be waiting to be written,
+	// it defaults to 1024.
+	BufferSize int
+	// Overflow is the policy when the buffer is full, it defaults to
+	// OverflowBlock.
+	Overflow OverflowPolicy
+}
+
+// ErrClosed is returned when flushing an AsyncLogger which has been closed.
+var ErrClosed = errors.New("logger closed", errors.C("logger_closed"))
+
+// AsyncLogger is a Logger which writes logs to another Logger in a background
+// goroutine, so that logging doesn't wait for the logs to be written.
+// Use Flush to wait for buffered logs to be written, e.g. before shutting
+// down, and Close to stop the background goroutine.
+//
+//	l := log.NewAsyncLogger(log.GetLogger(), log.AsyncConfig{})
+//	log.SetLogger(l)
+//	defer log.Close()
+type AsyncLogger struct {
+	next     Logger
+	overflow OverflowPolicy
+
+	// mu guards closed, which is set once queue is closed
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan asyncItem
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+type asyncItem struct {
+	ctx   context.Context
+	entry Entry
+	// flushed is closed once the logs before it have been written, it's
+	// only set for flushes
+	flushed chan struct{}
+}
+
+// NewAsyncLogger returns an AsyncLogger writing logs to next, and starts its
+// background goroutine.
+func NewAsyncLogger(next Logger, cfg AsyncConfig) *AsyncLogger {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1024
+	}
+	l := &AsyncLogger{
+		next:     next,
+		overflow: cfg.Overflow,
+		queue:    make(chan asyncItem, cfg.BufferSize),
+		done:     make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+func (l *AsyncLogger) run() {
+	defer close(l.done)
+	for item := range l.queue {
+		if item.flushed != nil {
+			close(item.flushed)
+			continue
+		}
+		writeEntry(item.ctx, l.next, item.entry)
+	}
+}
+
+// Log buffers the log to be written by the background goroutine. Since the
+// log hasn't been written yet, it always returns an empty string. Logs after
+// Close are dropped.
+func (l *AsyncLogger) Log(ctx context.Context, e Entry) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		l.dropped.Add(1)
+		return ""
+	}
+	item := asyncItem{ctx: ctx, entry: e}
+	if l.overflow == OverflowDrop {
+		select {
+		case l.queue <- item:
+		default:
+			l.dropped.Add(1)
+		}
+		return ""
+	}
+	l.queue <- item
+	return ""
+}
+
+// Flush waits for the logs buffered before it was called to be written.
+// It returns ctx's error if ctx is done first, or ErrClosed if the logger
+// has been closed.
+func (l *AsyncLogger) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	err := func() error {
+		l.mu.RLock()
+		defer l.mu.RUnlock()
+		if l.closed {
+			return ErrClosed
+		}
+		select {
+		case l.queue <- asyncItem{flushed: flushed}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}()
+	if err != nil {
+		return err
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close writes any buffered logs and stops the background goroutine.
+// It's safe to call more than once.
+func (l *AsyncLogger) Close() {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mu.Unlock()
+	<-l.done
+}
+
+// Dropped returns the number of logs dropped because the buffer was full
+// or the logger was closed.
+func (l *AsyncLogger) Dropped() int64 {
+	return l.dropped.Load()
+}
+
+// Flush waits for buffered logs to be written if the global logger buffers
+// logs, like AsyncLogger, otherwise it returns nil immediately.
+func Flush(ctx context.Context) error {
+	if f, ok := GetLogger().(interface{ Flush(context.Context) error }); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// Close writes any buffered logs and stops the global logger if it has a
+// Close method, like AsyncLogger.
+func Close() {
+	if c, ok := GetLogger().(interface{ Close() }); ok {
+		c.Close()
+	}
+}
+
+var _ Logger = (*AsyncLogger)(nil)
@@ -0,0 +1,130 @@
+package log
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/peterlabuschagne/jettison/errors"
+	"github.com/peterlabuschagne/jettison/jtest"
+)
+
+func TestAsyncLoggerFlush(t *testing.T) {
+	const n = 1000
+
+	var (
+		mu      sync.Mutex
+		written []string
+	)
+	l := NewAsyncLogger(loggerFunc(func(e Entry) {
+		mu.Lock()
+		defer mu.Unlock()
+		written = append(written, e.Message)
+	}), AsyncConfig{BufferSize: 10})
+	t.Cleanup(l.Close)
+	SetLoggerForTesting(t, l)
+
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		Info(ctx, strconv.Itoa(i))
+	}
+	jtest.RequireNil(t, Flush(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, written, n)
+	for i, msg := range written {
+		assert.Equal(t, strconv.Itoa(i), msg)
+	}
+	assert.Zero(t, l.Dropped())
+}
+
+func TestAsyncLoggerDrop(t *testing.T) {
+	unblock := make(chan struct{})
+	var written int
+	l := NewAsyncLogger(loggerFunc(func(e Entry) {
+		<-unblock
+		written++
+	}), AsyncConfig{BufferSize: 1, Overflow: OverflowDrop})
+	SetLoggerForTesting(t, l)
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		Info(ctx, "msg")
+	}
+	close(unblock)
+	Close()
+
+	// At most one log is being written and one is buffered
+	assert.LessOrEqual(t, written, 2)
+	assert.Equal(t, int64(10-written), l.Dropped())
+}
+
+func TestAsyncLoggerClose(t *testing.T) {
+	var written int
+	l := NewAsyncLogger(loggerFunc(func(e Entry) {
+		written++
+	}), AsyncConfig{})
+	SetLoggerForTesting(t, l)
+
+	ctx := context.Background()
+	Info(ctx, "before")
+	Close()
+	assert.Equal(t, 1, written)
+
+	Info(ctx, "after")
+	Close()
+	assert.Equal(t, 1, written)
+	assert.Equal(t, int64(1), l.Dropped())
+	jtest.Require(t, ErrClosed, Flush(ctx))
+}
+
+func TestAsyncLoggerFlushTimeout(t *testing.T) {
+	unblock := make(chan struct{})
+	l := NewAsyncLogger(loggerFunc(func(e Entry) {
+		<-unblock
+	}), AsyncConfig{})
+	t.Cleanup(l.Close)
+	t.Cleanup(func() { close(unblock) })
+
+	l.Log(context.Background(), Entry{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	assert.True(t, errors.Is(l.Flush(ctx), context.DeadlineExceeded))
+}
+
+func TestFlushSyncLogger(t *testing.T) {
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {}))
+	jtest.RequireNil(t, Flush(context.Background()))
+	Close()
+}
+
+func TestInterfaceFlush(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		written int
+	)
+	l := NewAsyncLogger(loggerFunc(func(e Entry) {
+		mu.Lock()
+		defer mu.Unlock()
+		written++
+	}), AsyncConfig{BufferSize: 10})
+	t.Cleanup(l.Close)
+	SetLoggerForTesting(t, l)
+
+	ctx := context.Background()
+	for _, li := range []Interface{Jettison{}, With(WithField("key", "value"))} {
+		for i := 0; i < 100; i++ {
+			li.Info(ctx, strconv.Itoa(i))
+		}
+		jtest.RequireNil(t, li.Flush(ctx))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 200, written)
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var clock atomic.Pointer[func() time.Time]
+
+// SetClock sets the function used to timestamp log entries, it defaults to
+// time.Now. Passing nil restores the default. WithTimestamp overrides the
+// clock for a single log.
+func SetClock(now func() time.Time) {
+	if now == nil {
+		clock.Store(nil)
+		return
+	}
+	clock.Store(&now)
+}
+
+// SetClockForTesting sets the clock for the duration of the test.
+func SetClockForTesting(t testing.TB, now func() time.Time) {
+	old := clock.Load()
+	t.Cleanup(func() {
+		clock.Store(old)
+	})
+	SetClock(now)
+}
+
+// WithTimestamp returns a jettison option to override the time of the log.
+func WithTimestamp(ts time.Time) Option {
+	return logOption(func(e *Entry) {
+		e.Timestamp = ts
+	})
+}
+
+func now() time.Time {
+	c := clock.Load()
+	if c == nil {
+		return time.Now()
+	}
+	return (*c)()
+}
+
+const (
+	// TimeFormatDefault restores the default rendering of timestamps, which is
+	// "15:04:05.000" for the command line logger and time.RFC3339Nano for
+	// JSONFormatter.
+	TimeFormatDefault = ""
+	// TimeFormatUnix renders timestamps as the number of seconds since the
+	// Unix epoch, a JSON number for JSONFormatter.
+	TimeFormatUnix = "unix"
+	// TimeFormatUnixMilli renders timestamps as the number of milliseconds
+	// since the Unix epoch, a JSON number for JSONFormatter.
+	TimeFormatUnixMilli = "unixmilli"
+)
+
+var timeFormat atomic.Pointer[string]
+
+// SetTimeFormat sets how the timestamps of logs are rendered by the command
+// line logger and JSONFormatter, either a layout for time.Time's Format,
+// e.g. time.RFC3339, or one of TimeFormatUnix and TimeFormatUnixMilli.
+// Only the rendering changes, Entry.Timestamp is unaffected.
+// TimeFormatDefault restores the default.
+//
+//	log.SetTimeFormat(log.TimeFormatUnixMilli)
+func SetTimeFormat(layout string) {
+	if layout == TimeFormatDefault {
+		timeFormat.Store(nil)
+		return
+	}
+	timeFormat.Store(&layout)
+}
+
+// SetTimeFormatForTesting sets the time format for the duration of the test.
+func SetTimeFormatForTesting(t testing.TB, layout string) {
+	old := timeFormat.Load()
+	t.Cleanup(func() {
+		timeFormat.Store(old)
+	})
+	SetTimeFormat(layout)
+}
+
+// formatTimestamp renders ts as set by SetTimeFormat. It returns false if
+// the default should be used, and whether the result is a number otherwise.
+func formatTimestamp(ts time.Time) (s string, number bool, ok bool) {
+	layout := timeFormat.Load()
+	if layout == nil {
+		return "", false, false
+	}
+	switch *layout {
+	case TimeFormatUnix:
+		return strconv.FormatInt(ts.Unix(), 10), true, true
+	case TimeFormatUnixMilli:
+		return strconv.FormatInt(ts.UnixMilli(), 10), true, true
+	}
+	return ts.Format(*layout), false, true
+}
@@ -0,0 +1,119 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClock(t *testing.T) {
+	frozen := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	override := time.Date(2020, 6, 7, 8, 9, 10, 0, time.UTC)
+
+	testCases := []struct {
+		name  string
+		clock func() time.Time
+		opts  []Option
+		expTS time.Time
+	}{
+		{
+			name:  "global clock",
+			clock: func() time.Time { return frozen },
+			expTS: frozen,
+		},
+		{
+			name:  "option overrides global clock",
+			clock: func() time.Time { return frozen },
+			opts:  []Option{WithTimestamp(override)},
+			expTS: override,
+		},
+		{
+			name:  "option without clock",
+			opts:  []Option{WithTimestamp(override)},
+			expTS: override,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetClockForTesting(t, tc.clock)
+			var entries []Entry
+			SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+				entries = append(entries, e)
+			}))
+
+			Info(context.Background(), "msg", tc.opts...)
+			assert.Len(t, entries, 1)
+			assert.Equal(t, tc.expTS, entries[0].Timestamp)
+		})
+	}
+}
+
+func TestClockDefault(t *testing.T) {
+	SetClockForTesting(t, nil)
+	before := time.Now()
+	ts := now()
+	assert.False(t, ts.Before(before))
+	assert.False(t, ts.After(time.Now()))
+}
+
+func TestSetTimeFormat(t *testing.T) {
+	frozen := time.Date(2023, 1, 2, 3, 4, 5, 600_000_000, time.UTC)
+	testCases := []struct {
+		name    string
+		layout  string
+		expCmd  string
+		expJSON string
+	}{
+		{
+			name:    "default",
+			layout:  TimeFormatDefault,
+			expCmd:  "03:04:05.600",
+			expJSON: `"timestamp":"2023-01-02T03:04:05.6Z"`,
+		},
+		{
+			name:    "layout",
+			layout:  time.RFC3339,
+			expCmd:  "2023-01-02T03:04:05Z",
+			expJSON: `"timestamp":"2023-01-02T03:04:05Z"`,
+		},
+		{
+			name:    "unix",
+			layout:  TimeFormatUnix,
+			expCmd:  "1672628645",
+			expJSON: `"timestamp":1672628645`,
+		},
+		{
+			name:    "unix milli",
+			layout:  TimeFormatUnixMilli,
+			expCmd:  "1672628645600",
+			expJSON: `"timestamp":1672628645600`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetClockForTesting(t, func() time.Time { return frozen })
+			SetTimeFormatForTesting(t, tc.layout)
+
+			var cmd bytes.Buffer
+			SetLoggerForTesting(t, NewCmdLogger(&cmd, false))
+			Info(context.Background(), "msg")
+			assert.Equal(t, "I "+tc.expCmd+" ", cmd.String()[:len(tc.expCmd)+3])
+
+			var buf bytes.Buffer
+			SetLoggerForTesting(t, newJSONLogger(&buf))
+			Info(context.Background(), "msg")
+			assert.Contains(t, buf.String(), tc.expJSON)
+			assert.Equal(t, 1, strings.Count(buf.String(), `"timestamp"`))
+
+			b, err := JSONFormatter{}.Format(Entry{Timestamp: frozen})
+			require.NoError(t, err)
+			assert.Contains(t, string(b), tc.expJSON)
+		})
+	}
+}
@@ -24,7 +24,10 @@ type CmdLogger struct {
 }
 
 func (c *CmdLogger) Log(_ context.Context, l Entry) string {
-	timestamp := l.Timestamp.Format("15:04:05.000")
+	timestamp, _, ok := formatTimestamp(l.Timestamp)
+	if !ok {
+		timestamp = l.Timestamp.Format("15:04:05.000")
+	}
 	if c.stripTime {
 		timestamp = "00:00:00.000"
 	}
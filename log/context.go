@@ -2,6 +2,9 @@ package log
 
 import (
 	"context"
+	"sort"
+	"sync/atomic"
+	"time"
 
 	"github.com/peterlabuschagne/jettison/models"
 )
@@ -21,6 +24,9 @@ type ContextOption interface {
 // to its key/value store. When a context containing jettison options is
 // passed to Info or Error, the options are automatically applied to
 // the resulting log.
+//
+// Keys already in the context are replaced by newer values with the same key,
+// see ContextWithKeyValues. The parent context is never modified.
 func ContextWith(ctx context.Context, opts ...ContextOption) context.Context {
 	var add []models.KeyValue
 	for _, o := range opts {
@@ -29,15 +35,30 @@ func ContextWith(ctx context.Context, opts ...ContextOption) context.Context {
 	return ContextWithKeyValues(ctx, add)
 }
 
+// ContextWithKeyValues returns a new context with the given key/value pairs
+// appended to its key/value store. Any pairs in ctx with the same key as one
+// of the added pairs are removed so that the newest value wins, duplicate keys
+// within add are all kept.
 func ContextWithKeyValues(ctx context.Context, add []models.KeyValue) context.Context {
 	if len(add) == 0 {
 		return ctx
 	}
-	kvs := append(ContextKeyValues(ctx), add...)
+	replaced := make(map[string]bool, len(add))
+	for _, kv := range add {
+		replaced[kv.Key] = true
+	}
+	var kvs []models.KeyValue
+	for _, kv := range ContextKeyValues(ctx) {
+		if !replaced[kv.Key] {
+			kvs = append(kvs, kv)
+		}
+	}
+	kvs = append(kvs, add...)
 	return context.WithValue(ctx, key, kvs)
 }
 
 // ContextKeyValues returns the list of jettison key values options contained in the given context.
+// The returned slice is a copy which may be modified by the caller.
 func ContextKeyValues(ctx context.Context) []models.KeyValue {
 	if ctx == nil {
 		return nil
@@ -50,3 +71,162 @@ func ContextKeyValues(ctx context.Context) []models.KeyValue {
 	copy(ret, kvs)
 	return ret
 }
+
+// levelKey is used to index the minimum level set by ContextWithLevel.
+type levelKey struct{}
+
+// ContextWithLevel returns a new context with the minimum level of logs
+// written with it set to level, overriding the level set by SetMinLevel, e.g.
+// to write debug logs for a single request:
+//
+//	if req.Debug {
+//	  ctx = log.ContextWithLevel(ctx, log.LevelDebug)
+//	}
+//
+// See SetMinLevel for how the levels of logs are compared.
+func ContextWithLevel(ctx context.Context, level Level) context.Context {
+	return context.WithValue(ctx, levelKey{}, level)
+}
+
+// ContextLevel returns the minimum level set with ContextWithLevel, and false
+// if there isn't one.
+func ContextLevel(ctx context.Context) (Level, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	l, ok := ctx.Value(levelKey{}).(Level)
+	return l, ok
+}
+
+// preparedKey is used to index the preparedContext set by PrepareContext.
+type preparedKey struct{}
+
+type preparedContext struct {
+	// kvs are the key/values of the context which was prepared
+	kvs []models.KeyValue
+	// sorted are kvs sorted by key
+	sorted []models.KeyValue
+}
+
+// PrepareContext returns a new context with its jettison key/values sorted
+// in advance, so that logs written with the context, e.g. in a loop, don't
+// have to sort them each time.
+//
+// Contexts derived from the returned context with ContextWith have different
+// key/values, so logs written with them don't use the prepared key/values.
+// They can be prepared again.
+func PrepareContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return nil
+	}
+	kvs, _ := ctx.Value(key).([]models.KeyValue)
+	if len(kvs) == 0 {
+		return ctx
+	}
+	sorted := make([]models.KeyValue, len(kvs))
+	copy(sorted, kvs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Key < sorted[j].Key
+	})
+	return context.WithValue(ctx, preparedKey{}, preparedContext{kvs: kvs, sorted: sorted})
+}
+
+// contextParameters returns the key/values of ctx to add to a log, sorted if
+// ctx was prepared with PrepareContext. The returned slice must not be
+// modified.
+func contextParameters(ctx context.Context) []models.KeyValue {
+	if ctx == nil {
+		return nil
+	}
+	kvs, _ := ctx.Value(key).([]models.KeyValue)
+	if len(kvs) == 0 {
+		return nil
+	}
+	p, ok := ctx.Value(preparedKey{}).(preparedContext)
+	if ok && len(p.kvs) == len(kvs) && &p.kvs[0] == &kvs[0] {
+		return p.sorted
+	}
+	return kvs
+}
+
+const (
+	// ContextErrorKey is the parameter added with the context's error,
+	// see SetContextDiagnostics.
+	ContextErrorKey = "ctx_error"
+	// ContextDeadlineKey is the parameter added with the time remaining until
+	// the context's deadline, see SetContextDiagnostics.
+	ContextDeadlineKey = "ctx_deadline_remaining"
+)
+
+var contextDiagnostics atomic.Bool
+
+// SetContextDiagnostics enables adding the state of the context to logs.
+// When enabled, logs with a cancelled or expired context have the
+// ContextErrorKey parameter, and logs with a context deadline have the
+// ContextDeadlineKey parameter, which is negative once the deadline has
+// passed. It is disabled by default.
+func SetContextDiagnostics(enabled bool) {
+	contextDiagnostics.Store(enabled)
+}
+
+// addContextDiagnostics adds the context's error and deadline to the entry
+// if enabled by SetContextDiagnostics.
+func addContextDiagnostics(ctx context.Context, e *Entry) {
+	if ctx == nil || !contextDiagnostics.Load() {
+		return
+	}
+	if err := ctx.Err(); err != nil {
+		e.SetKey(ContextErrorKey, err.Error())
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		e.SetKey(ContextDeadlineKey, deadline.Sub(now()).Round(time.Millisecond).String())
+	}
+}
+
+// traceIDKey is used to index the trace ID set with WithTraceID.
+type traceIDKey struct{}
+
+var traceIDFunc atomic.Pointer[func(context.Context) string]
+
+// WithTraceID returns a new context with the given trace or correlation ID,
+// which is added to the TraceID field of logs written with the context.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// SetTraceIDFunc sets a function returning the trace ID of contexts without
+// one set by WithTraceID, e.g. to use the trace ID of OpenTelemetry spans
+// without this package depending on OpenTelemetry:
+//
+//	log.SetTraceIDFunc(func(ctx context.Context) string {
+//	  sc := trace.SpanContextFromContext(ctx)
+//	  if !sc.HasTraceID() {
+//	    return ""
+//	  }
+//	  return sc.TraceID().String()
+//	})
+//
+// A nil fn removes the function, which is the default.
+func SetTraceIDFunc(fn func(context.Context) string) {
+	if fn == nil {
+		traceIDFunc.Store(nil)
+		return
+	}
+	traceIDFunc.Store(&fn)
+}
+
+// TraceIDFromContext returns the trace ID added to logs written with ctx.
+// An ID set with WithTraceID takes precedence over the function set with
+// SetTraceIDFunc. It returns an empty string if ctx has no trace ID.
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, _ := ctx.Value(traceIDKey{}).(string); id != "" {
+		return id
+	}
+	if fn := traceIDFunc.Load(); fn != nil {
+		return (*fn)(ctx)
+	}
+	return ""
+}
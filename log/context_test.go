@@ -3,6 +3,7 @@ package log_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -48,6 +49,18 @@ func TestContextWith(t *testing.T) {
 				{Key: "two", Value: "2"},
 			},
 		},
+		{
+			name: "newest value wins",
+			ctx: log.ContextWith(context.Background(),
+				j.KV("one", "1"),
+				j.KV("two", "2"),
+			),
+			opts: []log.ContextOption{j.KV("one", "uno")},
+			expKVs: []models.KeyValue{
+				{Key: "two", Value: "2"},
+				{Key: "one", Value: "uno"},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -57,3 +70,157 @@ func TestContextWith(t *testing.T) {
 		})
 	}
 }
+
+func TestContextWithNested(t *testing.T) {
+	parent := log.ContextWith(context.Background(), j.KV("request", "abc"))
+	child := log.ContextWith(parent, j.KV("user", "bob"))
+	sibling := log.ContextWith(parent, j.KV("request", "def"))
+	grandchild := log.ContextWith(child, j.KV("step", "1"))
+
+	assert.Equal(t, []models.KeyValue{
+		{Key: "request", Value: "abc"},
+	}, log.ContextKeyValues(parent))
+	assert.Equal(t, []models.KeyValue{
+		{Key: "request", Value: "abc"},
+		{Key: "user", Value: "bob"},
+	}, log.ContextKeyValues(child))
+	assert.Equal(t, []models.KeyValue{
+		{Key: "request", Value: "def"},
+	}, log.ContextKeyValues(sibling))
+	assert.Equal(t, []models.KeyValue{
+		{Key: "request", Value: "abc"},
+		{Key: "user", Value: "bob"},
+		{Key: "step", Value: "1"},
+	}, log.ContextKeyValues(grandchild))
+}
+
+func TestContextDiagnostics(t *testing.T) {
+	now := time.Now()
+	log.SetClockForTesting(t, func() time.Time { return now })
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	withDeadline, cancel := context.WithDeadline(context.Background(), now.Add(time.Hour))
+	defer cancel()
+	expired, cancel := context.WithDeadline(context.Background(), now.Add(-2*time.Second))
+	defer cancel()
+
+	testCases := []struct {
+		name      string
+		disabled  bool
+		ctx       context.Context
+		expParams []models.KeyValue
+	}{
+		{name: "nil context"},
+		{name: "background", ctx: context.Background()},
+		{
+			name:     "disabled",
+			disabled: true,
+			ctx:      cancelled,
+		},
+		{
+			name: "cancelled",
+			ctx:  cancelled,
+			expParams: []models.KeyValue{
+				{Key: "ctx_error", Value: "context canceled"},
+			},
+		},
+		{
+			name: "deadline",
+			ctx:  withDeadline,
+			expParams: []models.KeyValue{
+				{Key: "ctx_deadline_remaining", Value: "1h0m0s"},
+			},
+		},
+		{
+			name: "expired",
+			ctx:  expired,
+			expParams: []models.KeyValue{
+				{Key: "ctx_deadline_remaining", Value: "-2s"},
+				{Key: "ctx_error", Value: "context deadline exceeded"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			log.SetContextDiagnostics(!tc.disabled)
+			t.Cleanup(func() { log.SetContextDiagnostics(false) })
+			tl := new(testLogger)
+			log.SetLoggerForTesting(t, tl)
+
+			log.Info(tc.ctx, "msg")
+			assert.Equal(t, tc.expParams, tl.logs[0].Parameters)
+		})
+	}
+}
+
+type spanKey struct{}
+
+func TestTraceID(t *testing.T) {
+	log.SetTraceIDFunc(func(ctx context.Context) string {
+		id, _ := ctx.Value(spanKey{}).(string)
+		return id
+	})
+	t.Cleanup(func() { log.SetTraceIDFunc(nil) })
+
+	withSpan := context.WithValue(context.Background(), spanKey{}, "span")
+
+	testCases := []struct {
+		name       string
+		ctx        context.Context
+		expTraceID string
+	}{
+		{name: "nil context"},
+		{name: "no trace id", ctx: context.Background()},
+		{name: "trace id", ctx: log.WithTraceID(context.Background(), "abc"), expTraceID: "abc"},
+		{name: "trace id func", ctx: withSpan, expTraceID: "span"},
+		{name: "trace id before func", ctx: log.WithTraceID(withSpan, "abc"), expTraceID: "abc"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tl := new(testLogger)
+			log.SetLoggerForTesting(t, tl)
+
+			log.Info(tc.ctx, "msg")
+			assert.Equal(t, tc.expTraceID, tl.logs[0].TraceID)
+			assert.Equal(t, tc.expTraceID, log.TraceIDFromContext(tc.ctx))
+		})
+	}
+}
+
+func TestPrepareContext(t *testing.T) {
+	tl := new(testLogger)
+	log.SetLoggerForTesting(t, tl)
+
+	ctx := log.ContextWith(context.Background(), j.KV("b", 2), j.KV("a", 1))
+	prepared := log.PrepareContext(ctx)
+	derived := log.ContextWith(prepared, j.KV("c", 3), j.KV("a", 4))
+
+	log.Info(prepared, "prepared")
+	log.Info(prepared, "with field", log.WithField("0", "first"))
+	log.Info(derived, "derived")
+	log.Info(log.PrepareContext(context.Background()), "empty")
+
+	assert.Equal(t, []models.KeyValue{
+		{Key: "a", Value: "1"},
+		{Key: "b", Value: "2"},
+	}, tl.logs[0].Parameters)
+	assert.Equal(t, []models.KeyValue{
+		{Key: "0", Value: "first"},
+		{Key: "a", Value: "1"},
+		{Key: "b", Value: "2"},
+	}, tl.logs[1].Parameters)
+	assert.Equal(t, []models.KeyValue{
+		{Key: "a", Value: "4"},
+		{Key: "b", Value: "2"},
+		{Key: "c", Value: "3"},
+	}, tl.logs[2].Parameters)
+	assert.Empty(t, tl.logs[3].Parameters)
+
+	// The prepared key/values aren't modified by logging
+	assert.Equal(t, log.ContextKeyValues(ctx), log.ContextKeyValues(prepared))
+
+	assert.Nil(t, log.PrepareContext(nil))
+}
@@ -0,0 +1,89 @@
+package log
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DedupedCountKey is the parameter added to deduplicated logs with the number
+// of identical logs which were dropped since the last one was written.
+const DedupedCountKey = "deduped_count"
+
+// dedupeCapacity is the maximum number of distinct logs tracked for
+// deduplication, the least recently written are forgotten first.
+const dedupeCapacity = 1024
+
+type dedupeState struct {
+	key     string
+	written time.Time
+	dropped int
+}
+
+var dedupers = struct {
+	sync.Mutex
+	window time.Duration
+	// lru is ordered most recently written first
+	lru  *list.List
+	keys map[string]*list.Element
+}{lru: list.New(), keys: make(map[string]*list.Element)}
+
+// SetDedupeWindow enables deduplicating logs, identical logs written within
+// d of each other are dropped. Logs are identical if they have the same
+// message and error code. When a log is written after some identical ones
+// were dropped, the number dropped is added as the DedupedCountKey parameter.
+// d <= 0 disables deduplication, which is the default.
+func SetDedupeWindow(d time.Duration) {
+	dedupers.Lock()
+	defer dedupers.Unlock()
+	dedupers.window = d
+}
+
+// ResetDedupe clears the deduplication state of all logs, so that the next
+// of each log will be written.
+func ResetDedupe() {
+	dedupers.Lock()
+	defer dedupers.Unlock()
+	dedupers.lru.Init()
+	dedupers.keys = make(map[string]*list.Element)
+}
+
+// dedupe returns true if the entry should be written,
+// adding the number of dropped logs to its parameters.
+func dedupe(e *Entry) bool {
+	dedupers.Lock()
+	defer dedupers.Unlock()
+	if dedupers.window <= 0 {
+		return true
+	}
+
+	key := e.Message
+	if e.ErrorCode != nil {
+		key += "\x00" + *e.ErrorCode
+	}
+	t := now()
+
+	el, ok := dedupers.keys[key]
+	if !ok {
+		el = dedupers.lru.PushFront(&dedupeState{key: key, written: t})
+		dedupers.keys[key] = el
+		if dedupers.lru.Len() > dedupeCapacity {
+			oldest := dedupers.lru.Remove(dedupers.lru.Back()).(*dedupeState)
+			delete(dedupers.keys, oldest.key)
+		}
+		return true
+	}
+
+	s := el.Value.(*dedupeState)
+	if t.Sub(s.written) < dedupers.window {
+		s.dropped++
+		return false
+	}
+	if s.dropped > 0 {
+		e.SetKey(DedupedCountKey, strconv.Itoa(s.dropped))
+	}
+	s.written, s.dropped = t, 0
+	dedupers.lru.MoveToFront(el)
+	return true
+}
@@ -0,0 +1,83 @@
+package log
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/peterlabuschagne/jettison/errors"
+)
+
+func setDedupeWindowForTesting(t *testing.T, d time.Duration) {
+	t.Cleanup(func() {
+		SetDedupeWindow(0)
+		ResetDedupe()
+	})
+	SetDedupeWindow(d)
+}
+
+func TestSetDedupeWindow(t *testing.T) {
+	setDedupeWindowForTesting(t, time.Minute)
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	SetClockForTesting(t, func() time.Time { return ts })
+
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		Info(ctx, "retrying", kv("i", i))
+		Error(ctx, errors.New("failed", errors.WithCode("ERR_1")))
+		Error(ctx, errors.New("failed", errors.WithCode("ERR_2")))
+		ts = ts.Add(20 * time.Second)
+	}
+	Info(ctx, "retrying", kv("i", 3))
+	Error(ctx, errors.New("failed", errors.WithCode("ERR_2")))
+
+	var act []string
+	for _, e := range entries {
+		var code string
+		if e.ErrorCode != nil {
+			code = " " + *e.ErrorCode
+		}
+		act = append(act, e.Message+code+parameterString(e.Parameters))
+	}
+	assert.Equal(t, []string{
+		"retrying[i=0]",
+		"failed ERR_1",
+		"failed ERR_2",
+		"retrying[deduped_count=2,i=3]",
+		"failed ERR_2[deduped_count=2]",
+	}, act)
+}
+
+func TestDedupeDisabled(t *testing.T) {
+	var n int
+	SetLoggerForTesting(t, loggerFunc(func(Entry) { n++ }))
+
+	for i := 0; i < 3; i++ {
+		Info(context.Background(), "msg")
+	}
+	assert.Equal(t, 3, n)
+}
+
+func TestDedupeCapacity(t *testing.T) {
+	setDedupeWindowForTesting(t, time.Hour)
+
+	var n int
+	SetLoggerForTesting(t, loggerFunc(func(Entry) { n++ }))
+
+	ctx := context.Background()
+	Info(ctx, "first")
+	for i := 0; i < dedupeCapacity; i++ {
+		Info(ctx, strconv.Itoa(i))
+	}
+	// first has been forgotten
+	Info(ctx, "first")
+	assert.Equal(t, dedupeCapacity+2, n)
+}
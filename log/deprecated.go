@@ -60,15 +60,15 @@ func Fatalln(v ...interface{}) {
 
 func print(v ...interface{}) string {
 	l := newEntry(fmt.Sprint(v...), LevelInfo, 3)
-	return logger.Log(context.TODO(), l)
+	return GetLogger().Log(context.TODO(), l)
 }
 
 func printf(format string, v ...interface{}) string {
 	l := newEntry(fmt.Sprintf(format, v...), LevelInfo, 3)
-	return logger.Log(context.TODO(), l)
+	return GetLogger().Log(context.TODO(), l)
 }
 
 func println(v ...interface{}) string {
 	l := newEntry(fmt.Sprintln(v...), LevelInfo, 3)
-	return logger.Log(context.TODO(), l)
+	return GetLogger().Log(context.TODO(), l)
 }
@@ -0,0 +1,104 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/peterlabuschagne/jettison/errors"
+	"github.com/peterlabuschagne/jettison/internal"
+)
+
+// FormatKey is the parameter added with the format of logs written with the
+// formatted functions, e.g. Infof, when SetFormatParameters is enabled.
+// The arguments are added as FormatKey + "_arg_0", FormatKey + "_arg_1"...
+const FormatKey = "format"
+
+var formatParameters atomic.Bool
+
+// SetFormatParameters enables adding the format and arguments of logs written
+// with the formatted functions, e.g. Infof, as parameters, so that logs can
+// be queried by their format or arguments rather than their message. It is
+// disabled by default.
+func SetFormatParameters(enabled bool) {
+	formatParameters.Store(enabled)
+}
+
+// withFormat returns an option adding the format and args as parameters if
+// SetFormatParameters is enabled.
+func withFormat(format string, args []any) Option {
+	return logOption(func(e *Entry) {
+		if !formatParameters.Load() {
+			return
+		}
+		e.SetKey(FormatKey, format)
+		for i, arg := range args {
+			e.SetKey(FormatKey+"_arg_"+strconv.Itoa(i), internal.Sprint(arg))
+		}
+	})
+}
+
+// withMessage returns an option replacing the message of the log.
+func withMessage(msg string) Option {
+	return logOption(func(e *Entry) {
+		e.Message = msg
+	})
+}
+
+// Debugf is like Debug, with the message formatted as by fmt.Sprintf.
+func Debugf(ctx context.Context, format string, args ...any) {
+	if !levelEnabled(ctx, LevelDebug) {
+		return
+	}
+	e, ok := makeEntry(ctx, fmt.Sprintf(format, args...), LevelDebug, withFormat(format, args))
+	if !ok {
+		return
+	}
+	writeEntry(ctx, GetLogger(), e)
+}
+
+// Infof is like Info, with the message formatted as by fmt.Sprintf.
+//
+//	log.Infof(ctx, "processed %d payments", n)
+func Infof(ctx context.Context, format string, args ...any) {
+	if !levelEnabled(ctx, LevelInfo) {
+		return
+	}
+	e, ok := makeEntry(ctx, fmt.Sprintf(format, args...), LevelInfo, withFormat(format, args))
+	if !ok {
+		return
+	}
+	writeEntry(ctx, GetLogger(), e)
+}
+
+// Warnf is like Warn, with the message formatted as by fmt.Sprintf.
+func Warnf(ctx context.Context, format string, args ...any) {
+	if !levelEnabled(ctx, LevelWarn) {
+		return
+	}
+	e, ok := makeEntry(ctx, fmt.Sprintf(format, args...), LevelWarn, withFormat(format, args))
+	if !ok {
+		return
+	}
+	writeEntry(ctx, GetLogger(), e)
+}
+
+// Errorf is like Error, with the message formatted as by fmt.Sprintf rather
+// than the error's message. The error is still included in the log.
+//
+//	log.Errorf(ctx, err, "failed to process payment %d", id)
+func Errorf(ctx context.Context, err error, format string, args ...any) {
+	if err == nil {
+		err = errors.New("nil error logged - this is probably a bug")
+	}
+	lvl := errorLevel(err)
+	if !levelEnabled(ctx, lvl) {
+		return
+	}
+	e, ok := makeEntry(ctx, fmt.Sprintf(format, args...), lvl, withFormat(format, args), WithError(err))
+	if !ok {
+		return
+	}
+	writeEntry(ctx, GetLogger(), e)
+}
@@ -0,0 +1,88 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/peterlabuschagne/jettison/errors"
+	"github.com/peterlabuschagne/jettison/models"
+)
+
+func setFormatParametersForTesting(t *testing.T) {
+	t.Cleanup(func() {
+		SetFormatParameters(false)
+	})
+	SetFormatParameters(true)
+}
+
+func TestFormatted(t *testing.T) {
+	setMinLevelForTesting(t, LevelDebug)
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	ctx := context.Background()
+	Debugf(ctx, "debug %d", 1)
+	Infof(ctx, "info %s", "two")
+	Warnf(ctx, "warn %v", 3.5)
+	Errorf(ctx, errors.New("boom", errors.C("boom")), "error %d", 4)
+
+	require.Len(t, entries, 4)
+	assert.Equal(t, []string{"debug 1", "info two", "warn 3.5", "error 4"},
+		[]string{entries[0].Message, entries[1].Message, entries[2].Message, entries[3].Message})
+	assert.Equal(t, []Level{LevelDebug, LevelInfo, LevelWarn, LevelError},
+		[]Level{entries[0].Level, entries[1].Level, entries[2].Level, entries[3].Level})
+	for _, e := range entries {
+		assert.Equal(t, "github.com/peterlabuschagne/jettison/log.TestFormatted", e.SourceFunc)
+		assert.Empty(t, e.Parameters)
+	}
+
+	require.NotNil(t, entries[3].ErrorCode)
+	assert.Equal(t, "boom", *entries[3].ErrorCode)
+	require.NotNil(t, entries[3].ErrorObject)
+	assert.Equal(t, "boom", entries[3].ErrorObject.Message)
+}
+
+func TestFormatParameters(t *testing.T) {
+	setFormatParametersForTesting(t)
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	ctx := context.Background()
+	Infof(ctx, "paid %d to %s", 10, "alice")
+	With(WithField("service", "payments")).Infof(ctx, "refunded %d", 5)
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, []models.KeyValue{
+		{Key: "format", Value: "paid %d to %s"},
+		{Key: "format_arg_0", Value: "10"},
+		{Key: "format_arg_1", Value: "alice"},
+	}, entries[0].Parameters)
+	assert.Equal(t, []models.KeyValue{
+		{Key: "format", Value: "refunded %d"},
+		{Key: "format_arg_0", Value: "5"},
+		{Key: "service", Value: "payments"},
+	}, entries[1].Parameters)
+	assert.Equal(t, "github.com/peterlabuschagne/jettison/log.TestFormatParameters", entries[1].SourceFunc)
+}
+
+func TestBoundErrorf(t *testing.T) {
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	With(WithField("service", "payments")).Errorf(context.Background(), errors.New("boom"), "failed %d", 1)
+
+	require.Len(t, entries, 1)
+	assert.Equal(t, "failed 1", entries[0].Message)
+	assert.Equal(t, LevelError, entries[0].Level)
+	assert.NotNil(t, entries[0].ErrorObject)
+	assert.Equal(t, []models.KeyValue{{Key: "service", Value: "payments"}}, entries[0].Parameters)
+}
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Formatter converts a log entry into the bytes written by a logger.
+type Formatter interface {
+	Format(e Entry) ([]byte, error)
+}
+
+// StreamFormatter is a Formatter which can also write a log entry directly
+// to a writer, without building it in memory first. The output of FormatTo
+// must be the output of Format followed by a newline.
+// Loggers created by SetFormatter and SetWriter use FormatTo with reused
+// buffers when the formatter implements it, reducing the memory used for
+// large entries, e.g. errors with deep stack traces.
+type StreamFormatter interface {
+	Formatter
+	FormatTo(w io.Writer, e Entry) error
+}
+
+// JSONFormatter formats log entries as single line JSON objects,
+// suitable for newline-delimited JSON log aggregation.
+// Timestamps are formatted using time.RFC3339Nano, unless changed by
+// SetTimeFormat.
+type JSONFormatter struct{}
+
+func (JSONFormatter) Format(e Entry) ([]byte, error) {
+	return json.Marshal(jsonEntry(e))
+}
+
+// FormatTo writes e to w as a newline terminated JSON object, with
+// a single call to w.Write.
+func (JSONFormatter) FormatTo(w io.Writer, e Entry) error {
+	return json.NewEncoder(w).Encode(jsonEntry(e))
+}
+
+// timestampEntry is an Entry with its timestamp rendered as set by
+// SetTimeFormat.
+type timestampEntry struct {
+	Entry
+	Timestamp any `json:"timestamp"`
+}
+
+// jsonEntry returns the value to marshal for e.
+func jsonEntry(e Entry) any {
+	ts, number, ok := formatTimestamp(e.Timestamp)
+	if !ok {
+		return e
+	}
+	if number {
+		return timestampEntry{Entry: e, Timestamp: json.Number(ts)}
+	}
+	return timestampEntry{Entry: e, Timestamp: ts}
+}
+
+var _ StreamFormatter = JSONFormatter{}
@@ -0,0 +1,227 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/peterlabuschagne/jettison/errors"
+	"github.com/peterlabuschagne/jettison/models"
+)
+
+func TestJSONFormatter(t *testing.T) {
+	testCases := []struct {
+		name    string
+		entry   Entry
+		expJSON string
+	}{
+		{
+			name: "timestamp is RFC3339Nano",
+			entry: Entry{
+				Message:   "msg",
+				Source:    "source.go:1",
+				Level:     LevelInfo,
+				Timestamp: time.Date(2023, 1, 2, 3, 4, 5, 600, time.UTC),
+			},
+			expJSON: `{"message":"msg","source":"source.go:1","level":"info","timestamp":"2023-01-02T03:04:05.0000006Z"}`,
+		},
+		{
+			name: "parameters and errors",
+			entry: Entry{
+				Message:    "msg",
+				Level:      LevelError,
+				Parameters: []models.KeyValue{{Key: "k", Value: "v"}},
+				ErrorObjects: []ErrorObject{
+					{Message: "one"},
+				},
+			},
+			expJSON: `{"message":"msg","source":"","level":"error","timestamp":"0001-01-01T00:00:00Z",` +
+				`"parameters":[{"key":"k","value":"v"}],` +
+				`"error_objects":[{"code":"","source":"","message":"one"}]}`,
+		},
+		{
+			name: "trace id",
+			entry: Entry{
+				Message: "msg",
+				Level:   LevelInfo,
+				TraceID: "abc",
+			},
+			expJSON: `{"message":"msg","source":"","level":"info","timestamp":"0001-01-01T00:00:00Z","trace_id":"abc"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := JSONFormatter{}.Format(tc.entry)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expJSON, string(b))
+		})
+	}
+}
+
+type messageFormatter struct{}
+
+func (messageFormatter) Format(e Entry) ([]byte, error) {
+	return []byte(e.Message), nil
+}
+
+func TestFormatLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	SetLoggerForTesting(t, newFormatLogger(buf, messageFormatter{}))
+
+	Info(context.Background(), "one")
+	Info(context.Background(), "two")
+
+	assert.Equal(t, "one\ntwo\n", buf.String())
+}
+
+func TestSetWriter(t *testing.T) {
+	SetLoggerForTesting(t, NewCmdLogger(io.Discard, true))
+
+	buf := new(bytes.Buffer)
+	SetWriter(buf)
+	Info(context.Background(), "writer keeps cmd format")
+	assert.True(t, strings.HasPrefix(buf.String(), "I 00:00:00.000 "), buf.String())
+	assert.True(t, strings.HasSuffix(buf.String(), ": writer keeps cmd format\n"), buf.String())
+
+	buf.Reset()
+	SetFormatter(messageFormatter{})
+	Info(context.Background(), "formatter keeps writer")
+	assert.Equal(t, "formatter keeps writer\n", buf.String())
+
+	other := new(bytes.Buffer)
+	SetWriter(other)
+	Info(context.Background(), "writer keeps formatter")
+	assert.Equal(t, "writer keeps formatter\n", other.String())
+}
+
+func TestSetWriterOtherLogger(t *testing.T) {
+	// The format of other loggers can't be kept, so JSON is written instead
+	SetLoggerForTesting(t, NopLogger{})
+
+	buf := new(bytes.Buffer)
+	SetWriter(buf)
+	Info(context.Background(), "json", WithTimestamp(time.Time{}))
+	var e Entry
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+	assert.Equal(t, "json", e.Message)
+	assert.Equal(t, LevelInfo, e.Level)
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+}
+
+func TestSetWriterConcurrent(t *testing.T) {
+	SetLoggerForTesting(t, NewCmdLogger(io.Discard, true))
+
+	w := &lineCounter{}
+	SetWriter(w)
+	SetFormatter(messageFormatter{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Info(context.Background(), "message")
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 50, w.writes)
+	assert.Equal(t, strings.Repeat("message\n", 50), w.buf.String())
+}
+
+// lineCounter counts calls to Write, it is not safe for concurrent use.
+type lineCounter struct {
+	buf    bytes.Buffer
+	writes int
+}
+
+func (w *lineCounter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.buf.Write(p)
+}
+
+// bufferedFormatter hides the FormatTo method of a StreamFormatter.
+type bufferedFormatter struct {
+	Formatter
+}
+
+func TestJSONFormatterFormatTo(t *testing.T) {
+	entries := []Entry{
+		{Message: "msg", Level: LevelInfo},
+		{
+			Message:     "<html> &  ",
+			Level:       LevelError,
+			Parameters:  []models.KeyValue{{Key: "k", Value: "v"}},
+			ErrorObject: &ErrorObject{Message: "one"},
+		},
+		deepErrorEntry(t),
+	}
+
+	for _, e := range entries {
+		b, err := JSONFormatter{}.Format(e)
+		require.NoError(t, err)
+
+		var streamed bytes.Buffer
+		w := &lineCounter{}
+		require.NoError(t, JSONFormatter{}.FormatTo(io.MultiWriter(&streamed, w), e))
+		assert.Equal(t, string(b)+"\n", streamed.String())
+		assert.Equal(t, 1, w.writes)
+
+		var buffered bytes.Buffer
+		res := newFormatLogger(&buffered, bufferedFormatter{JSONFormatter{}}).Log(context.Background(), e)
+		streamed.Reset()
+		assert.Equal(t, res, newJSONLogger(&streamed).Log(context.Background(), e))
+		assert.Equal(t, buffered.String(), streamed.String())
+
+		streamed.Reset()
+		newJSONLogger(&streamed).writeEntry(context.Background(), e)
+		assert.Equal(t, buffered.String(), streamed.String())
+	}
+}
+
+// deepErrorEntry returns the log of an error with several hops, each with
+// a deep stack trace.
+func deepErrorEntry(tb testing.TB) Entry {
+	var wrap func(depth int, err error) error
+	wrap = func(depth int, err error) error {
+		if depth > 0 {
+			return wrap(depth-1, err)
+		}
+		return errors.Wrap(err, "hop", errors.WithStackTrace(), kv("key", "value"))
+	}
+	var err error = errors.New("root", errors.WithStackTrace())
+	for i := 0; i < 5; i++ {
+		err = wrap(50*i, err)
+	}
+
+	l := NewCaptureLogger()
+	SetLoggerForTesting(tb, l)
+	Error(context.Background(), err)
+	return l.Entries()[0]
+}
+
+func BenchmarkFormatLogger(b *testing.B) {
+	e := deepErrorEntry(b)
+	run := func(b *testing.B, f Formatter) {
+		l := newFormatLogger(io.Discard, f)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			l.writeEntry(context.Background(), e)
+		}
+	}
+	b.Run("buffered", func(b *testing.B) {
+		run(b, bufferedFormatter{JSONFormatter{}})
+	})
+	b.Run("streamed", func(b *testing.B) {
+		run(b, JSONFormatter{})
+	})
+}
@@ -0,0 +1,39 @@
+package log
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+)
+
+var includeGoroutineID atomic.Bool
+
+// SetIncludeGoroutineID enables adding the ID of the goroutine which wrote
+// each log as its GoroutineID, to help debug concurrency issues. Goroutine
+// IDs are only meaningful for correlating logs from the same run of
+// a process, since they're reused between processes.
+// Finding the ID requires formatting the goroutine's stack, so it's disabled
+// by default.
+func SetIncludeGoroutineID(enabled bool) {
+	includeGoroutineID.Store(enabled)
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+// goroutineID returns the ID of the current goroutine, parsed from the
+// header of its stack, e.g. "goroutine 18 [running]:". It returns 0 if the
+// header can't be parsed.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, err := strconv.ParseUint(string(b), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
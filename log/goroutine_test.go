@@ -0,0 +1,38 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetIncludeGoroutineID(t *testing.T) {
+	l := NewCaptureLogger()
+	SetLoggerForTesting(t, l)
+	ctx := context.Background()
+
+	Info(ctx, "disabled")
+
+	SetIncludeGoroutineID(true)
+	t.Cleanup(func() { SetIncludeGoroutineID(false) })
+	Info(ctx, "enabled")
+	Info(ctx, "same goroutine")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		Info(ctx, "other goroutine")
+	}()
+	wg.Wait()
+
+	entries := l.Entries()
+	require.Len(t, entries, 4)
+	assert.Zero(t, entries[0].GoroutineID)
+	assert.NotZero(t, entries[1].GoroutineID)
+	assert.Equal(t, entries[1].GoroutineID, entries[2].GoroutineID)
+	assert.NotZero(t, entries[3].GoroutineID)
+	assert.NotEqual(t, entries[1].GoroutineID, entries[3].GoroutineID)
+}
@@ -0,0 +1,72 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Hook is called for every log written by Debug, Info, Warn and Error,
+// e.g. to count logs by level and error code.
+type Hook interface {
+	// Fire is called synchronously with the complete entry, after options,
+	// sampling and redaction, and before it is written to the logger.
+	// Each hook is given its own copy of the entry, so changes made by a
+	// hook are not seen by other hooks or the logger.
+	Fire(e Entry) error
+}
+
+// HookFunc is a function implementing the Hook interface.
+type HookFunc func(e Entry) error
+
+func (f HookFunc) Fire(e Entry) error {
+	return f(e)
+}
+
+var hooks struct {
+	sync.RWMutex
+	list []Hook
+}
+
+var hookErrorHandler atomic.Pointer[func(error)]
+
+// AddHook adds a hook which is fired for every log.
+func AddHook(h Hook) {
+	hooks.Lock()
+	defer hooks.Unlock()
+	hooks.list = append(hooks.list, h)
+}
+
+// ResetHooks removes all hooks added with AddHook.
+func ResetHooks() {
+	hooks.Lock()
+	defer hooks.Unlock()
+	hooks.list = nil
+}
+
+// SetHookErrorHandler sets a function which is called with the error
+// returned by any failing hook. A failing hook doesn't prevent the log from
+// being written, or the other hooks from firing. By default hook errors
+// are discarded.
+func SetHookErrorHandler(f func(error)) {
+	if f == nil {
+		hookErrorHandler.Store(nil)
+		return
+	}
+	hookErrorHandler.Store(&f)
+}
+
+func fireHooks(e Entry) {
+	hooks.RLock()
+	list := hooks.list
+	hooks.RUnlock()
+
+	for _, h := range list {
+		err := h.Fire(e.Clone())
+		if err == nil {
+			continue
+		}
+		if f := hookErrorHandler.Load(); f != nil {
+			(*f)(err)
+		}
+	}
+}
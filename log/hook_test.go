@@ -0,0 +1,132 @@
+package log
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/peterlabuschagne/jettison/errors"
+	"github.com/peterlabuschagne/jettison/models"
+)
+
+func TestAddHook(t *testing.T) {
+	t.Cleanup(ResetHooks)
+	t.Cleanup(func() { SetHookErrorHandler(nil) })
+
+	var (
+		written []string
+		fired   []string
+		errs    []error
+	)
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		written = append(written, e.Message)
+	}))
+	SetHookErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+
+	errHook := errors.New("hook failed")
+	AddHook(HookFunc(func(e Entry) error {
+		return errHook
+	}))
+	AddHook(HookFunc(func(e Entry) error {
+		var code string
+		if e.ErrorCode != nil {
+			code = *e.ErrorCode
+		}
+		fired = append(fired, string(e.Level)+" "+code+" "+parameterString(e.Parameters))
+		return nil
+	}))
+
+	ctx := context.Background()
+	Info(ctx, "info", kv("k", "v"))
+	Error(ctx, errors.New("err", errors.WithCode("ERR_1")))
+
+	assert.Equal(t, []string{
+		"info  [k=v]",
+		"error ERR_1 ",
+	}, fired)
+	assert.Equal(t, []string{"info", "err"}, written)
+	assert.Equal(t, []error{errHook, errHook}, errs)
+}
+
+func TestHookNotFiredForDroppedLogs(t *testing.T) {
+	t.Cleanup(ResetHooks)
+	setMinLevelForTesting(t, LevelInfo)
+	SetLoggerForTesting(t, loggerFunc(func(Entry) {}))
+
+	var n int
+	AddHook(HookFunc(func(Entry) error {
+		n++
+		return nil
+	}))
+	Debug(context.Background(), "dropped")
+	Info(context.Background(), "written")
+	assert.Equal(t, 1, n)
+}
+
+func TestAddHookConcurrent(t *testing.T) {
+	t.Cleanup(ResetHooks)
+	SetLoggerForTesting(t, NewCmdLogger(io.Discard, true))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			AddHook(HookFunc(func(Entry) error { return nil }))
+		}()
+		go func() {
+			defer wg.Done()
+			Info(context.Background(), "msg", WithField("k", "v"))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestHookCannotModifyEntry(t *testing.T) {
+	t.Cleanup(ResetHooks)
+
+	var written Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		written = e
+	}))
+	var seen []Entry
+	mutate := HookFunc(func(e Entry) error {
+		seen = append(seen, e.Clone())
+		e.Parameters[0].Value = "mutated"
+		e.Parameters = append(e.Parameters, models.KeyValue{Key: "added"})
+		*e.ErrorCode = "mutated"
+		e.ErrorObject.Parameters[0].Value = "mutated"
+		e.ErrorObject.StackTrace[0].Content[0] = "mutated"
+		e.ErrorObject.Fields["k"] = "mutated"
+		return nil
+	})
+	AddHook(mutate)
+	AddHook(mutate)
+
+	err := errors.New("err", errors.WithCode("ERR_1"), errors.WithKV("k", "v"))
+	Error(context.Background(), err, kv("p", "v"))
+
+	assert.Len(t, seen, 2)
+	assert.Equal(t, seen[0], seen[1])
+	assert.Equal(t, seen[0], written)
+	assert.Equal(t, "v", written.Parameters[0].Value)
+	assert.Equal(t, "ERR_1", *written.ErrorCode)
+	assert.Equal(t, map[string]string{"k": "v"}, written.ErrorObject.Fields)
+}
+
+func TestContextNotModifiedByLogger(t *testing.T) {
+	ctx := ContextWith(context.Background(), kv("k", "v"))
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		for i := range e.Parameters {
+			e.Parameters[i].Value = "mutated"
+		}
+	}))
+
+	Info(ctx, "msg")
+	assert.Equal(t, []models.KeyValue{{Key: "k", Value: "v"}}, ContextKeyValues(ctx))
+}
@@ -4,21 +4,91 @@ import (
 	"context"
 	"fmt"
 	"sort"
-	"time"
+	"strings"
+	"sync/atomic"
 
 	"github.com/go-stack/stack"
 
 	"github.com/peterlabuschagne/jettison/errors"
 	"github.com/peterlabuschagne/jettison/internal"
+	"github.com/peterlabuschagne/jettison/models"
 	"github.com/peterlabuschagne/jettison/trace"
 )
 
 const (
 	LevelInfo  Level = "info"
+	LevelWarn  Level = "warn"
 	LevelError Level = "error"
 	LevelDebug Level = "debug"
 )
 
+// ErrUnknownLevel is returned by ParseLevel for strings which aren't a level.
+var ErrUnknownLevel = errors.New("unknown log level", errors.C("unknown_log_level"))
+
+// ParseLevel returns the level named by s, ignoring case, e.g. to read the
+// level for SetMinLevel from config. It returns ErrUnknownLevel if s isn't
+// one of the defined levels.
+func ParseLevel(s string) (Level, error) {
+	l := Level(strings.ToLower(s))
+	if !l.Valid() {
+		return "", errors.Wrap(ErrUnknownLevel, "", errors.WithKV("level", s))
+	}
+	return l, nil
+}
+
+// Valid returns true if l is one of the defined levels.
+func (l Level) Valid() bool {
+	_, ok := levelOrder[l]
+	return ok
+}
+
+func (l Level) String() string {
+	return string(l)
+}
+
+// levelOrder ranks the levels for filtering with SetMinLevel.
+var levelOrder = map[Level]int{
+	LevelDebug: 0,
+	LevelInfo:  1,
+	LevelWarn:  2,
+	LevelError: 3,
+}
+
+var minLevel atomic.Pointer[Level]
+
+// SetMinLevel sets the minimum level of logs written by Debug, Info, Warn and
+// Error, logs below this level are discarded. The levels are ordered
+// LevelDebug < LevelInfo < LevelWarn < LevelError.
+//
+// The level of a log is the one set with WithLevel if given, otherwise the
+// level of the function. It's compared with the minimum level set for the
+// log's context with ContextWithLevel if there is one, otherwise the level set
+// by SetMinLevel. So a WithLevel option takes precedence over the context's
+// level, which takes precedence over the global level.
+func SetMinLevel(l Level) {
+	minLevel.Store(&l)
+}
+
+// GetMinLevel returns the minimum level of logs that are written,
+// it defaults to LevelDebug.
+func GetMinLevel() Level {
+	l := minLevel.Load()
+	if l == nil {
+		return LevelDebug
+	}
+	return *l
+}
+
+// levelEnabled returns true if logs at the given level should be written
+// for ctx, see SetMinLevel.
+func levelEnabled(ctx context.Context, l Level) bool {
+	minLvl, ok := ContextLevel(ctx)
+	if !ok {
+		minLvl = GetMinLevel()
+	}
+	return levelOrder[normalLevel(l)] >= levelOrder[minLvl]
+}
+
 type logOption func(*Entry)
 
 func (o logOption) ApplyToLog(e *Entry) {
@@ -27,10 +97,39 @@ func (o logOption) ApplyToLog(e *Entry) {
 
 // WithLevel returns a jettison option to override the default log level.
 // It only works when provided as option to log package functions.
+// The log is filtered by the given level, see SetMinLevel.
 func WithLevel(level Level) Option {
-	return logOption(func(e *Entry) {
-		e.Level = level
-	})
+	return withLevel(level)
+}
+
+// withLevel is a separate type so that the level of a log can be found before
+// its options are applied.
+type withLevel Level
+
+func (l withLevel) ApplyToLog(e *Entry) {
+	e.Level = Level(l)
+}
+
+// levelOption is implemented by options which set the level of a log.
+type levelOption interface {
+	logLevel() (Level, bool)
+}
+
+func (l withLevel) logLevel() (Level, bool) {
+	return Level(l), true
+}
+
+// logLevel returns the level the log will be written at, which is lvl unless
+// it's set by one of the options.
+func logLevel(lvl Level, opts []Option) Level {
+	for _, o := range opts {
+		if lo, ok := o.(levelOption); ok {
+			if l, ok := lo.logLevel(); ok {
+				lvl = l
+			}
+		}
+	}
+	return lvl
 }
 
 // WithError returns a jettison option to add a structured error as part of
@@ -44,22 +143,71 @@ func WithError(err error) Option {
 		if len(codes) > 0 {
 			e.ErrorCode = &codes[0]
 		}
+		if errorFingerprints.Load() {
+			e.ErrorFingerprint = errors.Fingerprint(err)
+		}
 		addErrors(e, err)
 	})
 }
 
+// WithField returns a jettison option to add a key/value pair to the log's
+// parameters. The value is formatted in the same way as j.KV.
+func WithField(key string, value interface{}) Option {
+	return logOption(func(e *Entry) {
+		e.SetKey(key, internal.Sprint(value))
+	})
+}
+
+// WithFields returns a jettison option to add multiple key/value pairs to the
+// log's parameters. The values are formatted in the same way as j.KV.
+func WithFields(fields map[string]interface{}) Option {
+	return logOption(func(e *Entry) {
+		for k, v := range fields {
+			e.SetKey(k, internal.Sprint(v))
+		}
+	})
+}
+
 type Option interface {
 	ApplyToLog(*Entry)
 }
 
 func Debug(ctx context.Context, msg string, opts ...Option) {
-	logger.Log(ctx, makeEntry(ctx, msg, LevelDebug, opts...))
+	if !levelEnabled(ctx, logLevel(LevelDebug, opts)) {
+		return
+	}
+	e, ok := makeEntry(ctx, msg, LevelDebug, opts...)
+	if !ok {
+		return
+	}
+	writeEntry(ctx, GetLogger(), e)
 }
 
 // Info writes a structured jettison log to the logger. Any jettison
 // key/value pairs contained in the given context are included in the log.
 func Info(ctx context.Context, msg string, opts ...Option) {
-	logger.Log(ctx, makeEntry(ctx, msg, LevelInfo, opts...))
+	if !levelEnabled(ctx, logLevel(LevelInfo, opts)) {
+		return
+	}
+	e, ok := makeEntry(ctx, msg, LevelInfo, opts...)
+	if !ok {
+		return
+	}
+	writeEntry(ctx, GetLogger(), e)
+}
+
+// Warn writes a structured jettison log at warning level to the logger, for
+// conditions which are recoverable but notable. Any jettison key/value pairs
+// contained in the given context are included in the log.
+func Warn(ctx context.Context, msg string, opts ...Option) {
+	if !levelEnabled(ctx, logLevel(LevelWarn, opts)) {
+		return
+	}
+	e, ok := makeEntry(ctx, msg, LevelWarn, opts...)
+	if !ok {
+		return
+	}
+	writeEntry(ctx, GetLogger(), e)
 }
 
 // Error writes a structured jettison log of the given error to the logger.
@@ -67,48 +215,167 @@ func Info(ctx context.Context, msg string, opts ...Option) {
 // then logged. Any jettison key/value pairs contained in the given context are
 // included in the log.
 // If err is nil, a new error is created.
+//
+// The log is written at the level set with errors.WithSeverity, or LevelError
+// by default. A WithLevel option takes precedence over the error's severity.
 func Error(ctx context.Context, err error, opts ...Option) {
 	if err == nil {
 		err = errors.New("nil error logged - this is probably a bug")
 	}
+	lvl := errorLevel(err)
+	if !levelEnabled(ctx, logLevel(lvl, opts)) {
+		return
+	}
 	opts = append(opts, WithError(err))
-	e := makeEntry(ctx, err.Error(), LevelError, opts...)
-	logger.Log(ctx, e)
+	e, ok := makeEntry(ctx, err.Error(), lvl, opts...)
+	if !ok {
+		return
+	}
+	writeEntry(ctx, GetLogger(), e)
 }
 
-func makeEntry(ctx context.Context, msg string, lvl Level, opts ...Option) Entry {
+// errorLevel returns the level to log err at, using the error's severity
+// if it's a known level.
+func errorLevel(err error) Level {
+	s, ok := errors.Severity(err)
+	if !ok {
+		return LevelError
+	}
+	if _, known := levelOrder[Level(s)]; !known {
+		return LevelError
+	}
+	return Level(s)
+}
+
+// makeEntry returns the entry to log and true, or false if the entry
+// should not be logged.
+func makeEntry(ctx context.Context, msg string, lvl Level, opts ...Option) (Entry, bool) {
 	l := newEntry(msg, lvl, 3)
 	for _, o := range opts {
 		o.ApplyToLog(&l)
 	}
-	l.Parameters = append(l.Parameters, ContextKeyValues(ctx)...)
+	normalize(&l)
+	if !sample(&l) || !dedupe(&l) {
+		return Entry{}, false
+	}
+	l.Parameters = append(l.Parameters, contextParameters(ctx)...)
+	resolveParameters(&l)
+	addContextDiagnostics(ctx, &l)
+	l.TraceID = TraceIDFromContext(ctx)
+	if includeGoroutineID.Load() {
+		l.GoroutineID = goroutineID()
+	}
+	redact(&l)
+	setErrorFields(&l)
 
 	// Sort the parameters for consistent logging.
-	sort.Slice(l.Parameters, func(i, j int) bool {
+	less := func(i, j int) bool {
 		return l.Parameters[i].Key < l.Parameters[j].Key
-	})
+	}
+	if !sort.SliceIsSorted(l.Parameters, less) {
+		sort.Slice(l.Parameters, less)
+	}
+	fireHooks(l)
+
+	return l, true
+}
+
+// resolveParameters computes the values of the entry's lazy key/values,
+// see models.Lazy, and truncates values longer than the limit set by
+// SetMaxValueLength. It's only called once an entry will be logged, so that
+// values aren't computed for logs which are dropped.
+func resolveParameters(e *Entry) {
+	resolve := func(kvs []models.KeyValue) []models.KeyValue {
+		return internal.TruncateValues(models.ResolveAll(kvs))
+	}
+	e.Parameters = resolve(e.Parameters)
+	if e.ErrorObject != nil {
+		e.ErrorObject.Parameters = resolve(e.ErrorObject.Parameters)
+	}
+	for i := range e.ErrorObjects {
+		e.ErrorObjects[i].Parameters = resolve(e.ErrorObjects[i].Parameters)
+	}
+}
 
-	return l
+// SetMaxValueLength limits the length in bytes of parameter values, longer
+// values are truncated with "...[truncated N bytes]" appended, where N is the
+// number of bytes removed. Multi-byte characters are never split.
+// The limit applies to logs, and to the key/values of errors created
+// afterwards by the errors package, so that large values aren't sent over
+// gRPC either. n <= 0 means no limit, which is the default.
+func SetMaxValueLength(n int) {
+	internal.SetMaxValueLength(n)
 }
 
+var errorFingerprints atomic.Bool
+
+// SetErrorFingerprints enables setting the ErrorFingerprint of logs with
+// errors, see errors.Fingerprint, so that logs of the same logical error can
+// be grouped. It is disabled by default.
+func SetErrorFingerprints(enabled bool) {
+	errorFingerprints.Store(enabled)
+}
+
+var errorTimestamps atomic.Bool
+
+// SetErrorTimestamps enables adding the time logged errors were created to
+// their error objects, see errors.GetTimestamps. It is disabled by default.
+func SetErrorTimestamps(enabled bool) {
+	errorTimestamps.Store(enabled)
+}
+
+// ErrorTagsKey is the parameter added with the comma separated tags of a
+// logged error, see errors.WithTags.
+const ErrorTagsKey = "error_tags"
+
 func addErrors(e *Entry, err error) {
 	if err == nil {
 		return
 	}
+	if tags := errors.GetTags(err); len(tags) > 0 {
+		e.SetKey(ErrorTagsKey, strings.Join(tags, ","))
+	}
+	placement := ErrorKVPlacement(errorKVPlacement.Load())
 	paths := errors.Flatten(err)
-	if len(paths) == 1 {
-		ent := errorEntry(paths[0])
-		e.Parameters = append(e.Parameters, ent.Parameters...)
-		e.ErrorObject = &ent
-	} else {
-		for _, p := range paths {
-			ent := errorEntry(p)
+	for _, p := range paths {
+		ent := errorEntry(p)
+		if placement != ErrorKVPlacementErrorObject {
 			e.Parameters = append(e.Parameters, ent.Parameters...)
+		}
+		if placement == ErrorKVPlacementRoot {
+			ent.Parameters = nil
+		}
+		if len(paths) == 1 {
+			e.ErrorObject = &ent
+		} else {
 			e.ErrorObjects = append(e.ErrorObjects, ent)
 		}
 	}
 }
 
+// ErrorKVPlacement sets where the key/value pairs of logged errors are added.
+type ErrorKVPlacement int32
+
+const (
+	// ErrorKVPlacementBoth adds the key/value pairs to both the log's
+	// parameters and its error objects, which is the default.
+	ErrorKVPlacementBoth ErrorKVPlacement = iota
+	// ErrorKVPlacementRoot only adds the key/value pairs to the log's
+	// parameters.
+	ErrorKVPlacementRoot
+	// ErrorKVPlacementErrorObject only adds the key/value pairs to the log's
+	// error objects, so they can't collide with the log's own parameters.
+	ErrorKVPlacementErrorObject
+)
+
+var errorKVPlacement atomic.Int32
+
+// SetErrorKVPlacement sets where the key/value pairs of logged errors are
+// added, see ErrorKVPlacement.
+func SetErrorKVPlacement(p ErrorKVPlacement) {
+	errorKVPlacement.Store(int32(p))
+}
+
 func errorEntry(errPath []error) ErrorObject {
 	if len(errPath) == 0 {
 		return ErrorObject{}
@@ -143,30 +410,105 @@ func errorEntry(errPath []error) ErrorObject {
 			e.Stack = append(e.Stack, je.Binary)
 		}
 		e.Parameters = append(e.Parameters, je.KV...)
+		// Use the lowest non-zero timestamp
+		if !je.Timestamp.IsZero() && errorTimestamps.Load() {
+			ts := je.Timestamp
+			e.Timestamp = &ts
+		}
 		if len(je.StackTrace) > 0 {
 			m.Add(je.StackTrace, je.Binary)
 		}
 	}
-	e.StackTrace = MakeElastic(m.FullTrace())
+	e.StackTrace = MakeElastic(limitFrames(m.FullTrace()))
 	return e
 }
 
+// setErrorFields sets the Fields of the entry's error objects from their
+// parameters, once they have been resolved and redacted.
+func setErrorFields(e *Entry) {
+	if e.ErrorObject != nil {
+		e.ErrorObject.Fields = errorFields(e.ErrorObject.Parameters)
+	}
+	for i := range e.ErrorObjects {
+		e.ErrorObjects[i].Fields = errorFields(e.ErrorObjects[i].Parameters)
+	}
+}
+
+func errorFields(kvs []models.KeyValue) map[string]string {
+	if len(kvs) == 0 {
+		return nil
+	}
+	fields := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		if _, ok := fields[kv.Key]; ok {
+			continue
+		}
+		fields[kv.Key] = kv.Value
+	}
+	return fields
+}
+
+var maxStackFrames atomic.Int64
+
+// SetMaxStackFrames limits the number of frames in the stack traces of logged
+// errors, which can be very long for errors wrapped across many services.
+// The innermost frames, closest to where the error was created, are kept,
+// followed by "... N more frames". It doesn't affect the errors themselves,
+// see errors.SetTraceMode to limit the frames captured by errors.
+// n <= 0 means no limit, which is the default.
+func SetMaxStackFrames(n int) {
+	maxStackFrames.Store(int64(n))
+}
+
+// limitFrames returns the first frames of trace, as set by SetMaxStackFrames.
+func limitFrames(trace []string) []string {
+	n := int(maxStackFrames.Load())
+	if n <= 0 || len(trace) <= n {
+		return trace
+	}
+	return append(trace[:n:n], fmt.Sprintf("... %d more frames", len(trace)-n))
+}
+
 // newEntry returns an Entry struct decorated with useful defaults - stackSkip
 // is the number of callstacks to skip in the stacktrace before pulling
 // out the `source` of the call to `jettison/log.XXX`.
 func newEntry(msg string, level Level, stackSkip int) Entry {
+	src, fn := callerSource(stackSkip)
 	return Entry{
-		Message:   msg,
-		Source:    fmt.Sprintf("%+v", stack.Caller(stackSkip)),
-		Level:     level,
-		Timestamp: time.Now(),
+		Message:    msg,
+		Source:     src,
+		SourceFunc: fn,
+		Level:      level,
+		Timestamp:  now(),
 	}
 }
 
+// callerSource returns the source and function name of a caller, skip follows
+// the same semantics as stack.Caller, i.e. a skip of 0 is the function calling
+// callerSource. The skip must include every frame in the log package, so that
+// the caller is outside of it.
+func callerSource(skip int) (string, string) {
+	c := stack.Caller(skip + 1)
+	return fmt.Sprintf("%+v", c), c.Frame().Function
+}
+
+// Interface is implemented by Jettison and the loggers returned by With, so
+// that code can log without depending on the package level functions.
+//
+// Flush was added after the other methods, so implementations outside of this
+// package must add it. It should wait for the logs written before it was
+// called to be delivered, or return nil straight away if logs are written
+// synchronously.
 type Interface interface {
 	Debug(ctx context.Context, msg string, ol ...Option)
 	Info(ctx context.Context, msg string, ol ...Option)
+	Warn(ctx context.Context, msg string, ol ...Option)
 	Error(ctx context.Context, err error, ol ...Option)
+	Debugf(ctx context.Context, format string, args ...any)
+	Infof(ctx context.Context, format string, args ...any)
+	Warnf(ctx context.Context, format string, args ...any)
+	Errorf(ctx context.Context, err error, format string, args ...any)
+	Flush(ctx context.Context) error
 }
 
 type Jettison struct{}
@@ -179,8 +521,33 @@ func (j Jettison) Info(ctx context.Context, msg string, ol ...Option) {
 	Info(ctx, msg, ol...)
 }
 
+func (j Jettison) Warn(ctx context.Context, msg string, ol ...Option) {
+	Warn(ctx, msg, ol...)
+}
+
 func (j Jettison) Error(ctx context.Context, err error, ol ...Option) {
 	Error(ctx, err, ol...)
 }
 
+func (j Jettison) Debugf(ctx context.Context, format string, args ...any) {
+	Debugf(ctx, format, args...)
+}
+
+func (j Jettison) Infof(ctx context.Context, format string, args ...any) {
+	Infof(ctx, format, args...)
+}
+
+func (j Jettison) Warnf(ctx context.Context, format string, args ...any) {
+	Warnf(ctx, format, args...)
+}
+
+func (j Jettison) Errorf(ctx context.Context, err error, format string, args ...any) {
+	Errorf(ctx, err, format, args...)
+}
+
+// Flush waits for the global logger's buffered logs to be written, see Flush.
+func (j Jettison) Flush(ctx context.Context) error {
+	return Flush(ctx)
+}
+
 var _ Interface = (*Jettison)(nil)
@@ -3,14 +3,17 @@ package log
 import (
 	"bytes"
 	"context"
+	stderrors "errors"
 	"fmt"
 	"io"
 	stdlib_log "log"
 	"testing"
+	"time"
 
 	"github.com/go-stack/stack"
 	"github.com/sebdah/goldie/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	jerrors "github.com/peterlabuschagne/jettison/errors"
 	"github.com/peterlabuschagne/jettison/internal"
@@ -145,6 +148,26 @@ func TestError(t *testing.T) {
 				WithCustomTrace("testservice", []string{"teststacktrace"}),
 			),
 		},
+		{
+			name: "kv",
+			err: jerrors.New("test",
+				source("testsource"),
+				jerrors.WithKV("err_key", "err_val"),
+				WithCustomTrace("testservice", []string{"teststacktrace"}),
+			),
+		},
+		{
+			name: "tags",
+			err: jerrors.Wrap(
+				jerrors.New("test",
+					source("testsource"),
+					jerrors.WithTags("external", "payment"),
+					WithCustomTrace("testservice", []string{"teststacktrace"}),
+				),
+				"wrap",
+				jerrors.WithTags("transient", "external"),
+			),
+		},
 		{
 			name: "context",
 			ctx:  ContextWith(context.Background(), kv("ctx_key", "ctx_val")),
@@ -237,6 +260,29 @@ func BenchmarkErrorCtx(b *testing.B) {
 	}
 }
 
+func BenchmarkPrepareContext(b *testing.B) {
+	SetLoggerForTesting(b, loggerFunc(func(Entry) {}))
+
+	ctx := context.Background()
+	for _, k := range []string{"request_id", "user_id", "method", "account_id", "client"} {
+		ctx = ContextWith(ctx, kv(k, "value"))
+	}
+
+	run := func(b *testing.B, ctx context.Context) {
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < 10000; j++ {
+				Info(ctx, "test message")
+			}
+		}
+	}
+	b.Run("unprepared", func(b *testing.B) {
+		run(b, ctx)
+	})
+	b.Run("prepared", func(b *testing.B) {
+		run(b, PrepareContext(ctx))
+	})
+}
+
 func BenchmarkStdLibLog(b *testing.B) {
 	var buf bytes.Buffer
 	l := stdlib_log.New(&buf, "", stdlib_log.LstdFlags)
@@ -367,7 +413,7 @@ func TestAddErrors(t *testing.T) {
 		},
 		{
 			name: "joined errors",
-			err: jerrors.Join(
+			err: stderrors.Join(
 				jerrors.New("one", jerrors.WithoutStackTrace()),
 				jerrors.New("two", jerrors.WithoutStackTrace()),
 			),
@@ -378,9 +424,9 @@ func TestAddErrors(t *testing.T) {
 		},
 		{
 			name: "joins in joins",
-			err: jerrors.Join(
+			err: stderrors.Join(
 				jerrors.New("one", jerrors.WithoutStackTrace()),
-				jerrors.Join(
+				stderrors.Join(
 					jerrors.New("two", jerrors.WithoutStackTrace()),
 					jerrors.New("three", jerrors.WithoutStackTrace()),
 				),
@@ -401,3 +447,477 @@ func TestAddErrors(t *testing.T) {
 		})
 	}
 }
+
+func setMinLevelForTesting(t *testing.T, l Level) {
+	old := GetMinLevel()
+	t.Cleanup(func() {
+		SetMinLevel(old)
+	})
+	SetMinLevel(l)
+}
+
+func TestMinLevel(t *testing.T) {
+	testCases := []struct {
+		name      string
+		minLevel  Level
+		expLevels []Level
+	}{
+		{
+			name:      "debug logs everything",
+			minLevel:  LevelDebug,
+			expLevels: []Level{LevelDebug, LevelInfo, LevelWarn, LevelError},
+		},
+		{
+			name:      "info drops debug",
+			minLevel:  LevelInfo,
+			expLevels: []Level{LevelInfo, LevelWarn, LevelError},
+		},
+		{
+			name:      "warn drops info",
+			minLevel:  LevelWarn,
+			expLevels: []Level{LevelWarn, LevelError},
+		},
+		{
+			name:      "error only",
+			minLevel:  LevelError,
+			expLevels: []Level{LevelError},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setMinLevelForTesting(t, tc.minLevel)
+			assert.Equal(t, tc.minLevel, GetMinLevel())
+
+			var levels []Level
+			SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+				levels = append(levels, e.Level)
+			}))
+			ctx := context.Background()
+			Debug(ctx, "debug")
+			Info(ctx, "info")
+			Warn(ctx, "warn")
+			Error(ctx, jerrors.New("error"))
+
+			assert.Equal(t, tc.expLevels, levels)
+		})
+	}
+}
+
+func TestContextWithLevel(t *testing.T) {
+	type logged struct {
+		msg   string
+		level Level
+	}
+	var logs []logged
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		logs = append(logs, logged{msg: e.Message, level: e.Level})
+	}))
+	setMinLevelForTesting(t, LevelInfo)
+	bg := context.Background()
+	debugCtx := ContextWithLevel(bg, LevelDebug)
+	warnCtx := ContextWithLevel(bg, LevelWarn)
+
+	testCases := []struct {
+		name    string
+		log     func()
+		expLogs []logged
+	}{
+		{
+			name:    "global level",
+			log:     func() { Debug(bg, "debug"); Info(bg, "info") },
+			expLogs: []logged{{"info", LevelInfo}},
+		},
+		{
+			name:    "context level below global level",
+			log:     func() { Debug(debugCtx, "debug"); Debugf(debugCtx, "debug%s", "f") },
+			expLogs: []logged{{"debug", LevelDebug}, {"debugf", LevelDebug}},
+		},
+		{
+			name: "context level above global level",
+			log: func() {
+				Info(warnCtx, "info")
+				Warn(warnCtx, "warn")
+				Error(warnCtx, jerrors.New("error"))
+			},
+			expLogs: []logged{{"warn", LevelWarn}, {"error", LevelError}},
+		},
+		{
+			name: "option level above context level",
+			log: func() {
+				Info(warnCtx, "info", WithLevel(LevelError))
+				With(WithLevel(LevelError)).Info(warnCtx, "bound")
+			},
+			expLogs: []logged{{"info", LevelError}, {"bound", LevelError}},
+		},
+		{
+			name: "option level below context level",
+			log: func() {
+				Warn(warnCtx, "warn", WithLevel(LevelInfo))
+				Error(bg, jerrors.New("error"), WithLevel(LevelDebug))
+				With(WithLevel(LevelDebug)).Info(bg, "bound")
+				Debug(debugCtx, "debug", WithLevel(LevelDebug))
+			},
+			expLogs: []logged{{"debug", LevelDebug}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs = nil
+			tc.log()
+			assert.Equal(t, tc.expLogs, logs)
+		})
+	}
+
+	l, ok := ContextLevel(warnCtx)
+	assert.True(t, ok)
+	assert.Equal(t, LevelWarn, l)
+	_, ok = ContextLevel(bg)
+	assert.False(t, ok)
+}
+
+func TestParseLevel(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expLevel Level
+		expErr   bool
+	}{
+		{in: "debug", expLevel: LevelDebug},
+		{in: "info", expLevel: LevelInfo},
+		{in: "warn", expLevel: LevelWarn},
+		{in: "error", expLevel: LevelError},
+		{in: "INFO", expLevel: LevelInfo},
+		{in: "Warn", expLevel: LevelWarn},
+		{in: "eRrOr", expLevel: LevelError},
+		{in: "", expErr: true},
+		{in: "warning", expErr: true},
+		{in: " info", expErr: true},
+		{in: "fatal", expErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			l, err := ParseLevel(tc.in)
+			if tc.expErr {
+				assert.True(t, jerrors.Is(err, ErrUnknownLevel))
+				assert.Equal(t, Level(""), l)
+				assert.False(t, Level(tc.in).Valid())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expLevel, l)
+			assert.True(t, l.Valid())
+			assert.Equal(t, string(tc.expLevel), l.String())
+		})
+	}
+}
+
+func TestErrorSeverity(t *testing.T) {
+	testCases := []struct {
+		name     string
+		minLevel Level
+		err      error
+		opts     []Option
+		expLevel []Level
+	}{
+		{
+			name:     "no severity",
+			err:      jerrors.New("error"),
+			expLevel: []Level{LevelError},
+		},
+		{
+			name:     "severity",
+			err:      jerrors.New("not found", jerrors.WithSeverity("info")),
+			expLevel: []Level{LevelInfo},
+		},
+		{
+			name:     "unknown severity",
+			err:      jerrors.New("error", jerrors.WithSeverity("critical")),
+			expLevel: []Level{LevelError},
+		},
+		{
+			name:     "explicit level wins",
+			err:      jerrors.New("not found", jerrors.WithSeverity("info")),
+			opts:     []Option{WithLevel(LevelWarn)},
+			expLevel: []Level{LevelWarn},
+		},
+		{
+			name:     "filtered by severity",
+			minLevel: LevelWarn,
+			err:      jerrors.New("not found", jerrors.WithSeverity("info")),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.minLevel != "" {
+				setMinLevelForTesting(t, tc.minLevel)
+			}
+			var levels []Level
+			SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+				levels = append(levels, e.Level)
+			}))
+			Error(context.Background(), tc.err, tc.opts...)
+			assert.Equal(t, tc.expLevel, levels)
+		})
+	}
+}
+
+type loggerFunc func(e Entry)
+
+func (f loggerFunc) Log(_ context.Context, e Entry) string {
+	f(e)
+	return ""
+}
+
+func TestWithFields(t *testing.T) {
+	var e Entry
+	SetLoggerForTesting(t, loggerFunc(func(entry Entry) {
+		e = entry
+	}))
+	ctx := ContextWith(context.Background(), kv("b_ctx", "ctx"))
+	Info(ctx, "msg",
+		WithField("d_field", 1),
+		WithFields(map[string]interface{}{
+			"c_field": true,
+			"a_field": []string{"not", "printed"},
+		}),
+	)
+	assert.Equal(t, []models.KeyValue{
+		{Key: "a_field", Value: "<slice>"},
+		{Key: "b_ctx", Value: "ctx"},
+		{Key: "c_field", Value: "true"},
+		{Key: "d_field", Value: "1"},
+	}, e.Parameters)
+}
+
+func TestErrorTimestamps(t *testing.T) {
+	created := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	jerrors.SetClockTesting(t, func() time.Time { return created })
+	err := jerrors.New("error")
+	jerrors.SetClockTesting(t, func() time.Time { return created.Add(time.Minute) })
+	err = jerrors.Wrap(err, "wrapped")
+
+	ent := errorEntry(jerrors.Flatten(err)[0])
+	assert.Nil(t, ent.Timestamp)
+
+	SetErrorTimestamps(true)
+	t.Cleanup(func() { SetErrorTimestamps(false) })
+
+	ent = errorEntry(jerrors.Flatten(err)[0])
+	if assert.NotNil(t, ent.Timestamp) {
+		assert.Equal(t, created, *ent.Timestamp)
+	}
+}
+
+func TestErrorKVPlacement(t *testing.T) {
+	err := jerrors.New("error", kv("error_key", "value"))
+	errKVs := []models.KeyValue{{Key: "error_key", Value: "value"}}
+	fieldKVs := []models.KeyValue{{Key: "field", Value: "value"}}
+
+	testCases := []struct {
+		name            string
+		placement       ErrorKVPlacement
+		expParameters   []models.KeyValue
+		expErrorObjects []models.KeyValue
+	}{
+		{
+			name:            "both",
+			placement:       ErrorKVPlacementBoth,
+			expParameters:   append(errKVs, fieldKVs...),
+			expErrorObjects: errKVs,
+		},
+		{
+			name:          "root",
+			placement:     ErrorKVPlacementRoot,
+			expParameters: append(errKVs, fieldKVs...),
+		},
+		{
+			name:            "error object",
+			placement:       ErrorKVPlacementErrorObject,
+			expParameters:   fieldKVs,
+			expErrorObjects: errKVs,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetErrorKVPlacement(tc.placement)
+			t.Cleanup(func() { SetErrorKVPlacement(ErrorKVPlacementBoth) })
+
+			var entries []Entry
+			SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+				entries = append(entries, e)
+			}))
+
+			ctx := context.Background()
+			Error(ctx, err, WithField("field", "value"))
+			Error(ctx, jerrors.Join(err, err))
+
+			require.Len(t, entries, 2)
+			assert.Equal(t, tc.expParameters, entries[0].Parameters)
+			require.NotNil(t, entries[0].ErrorObject)
+			assert.Equal(t, tc.expErrorObjects, entries[0].ErrorObject.Parameters)
+			require.Len(t, entries[1].ErrorObjects, 2)
+			for _, eo := range entries[1].ErrorObjects {
+				assert.Equal(t, tc.expErrorObjects, eo.Parameters)
+			}
+		})
+	}
+}
+
+func TestErrorFields(t *testing.T) {
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	err := jerrors.New("error", kv("id", "inner"), kv("user", "alice"))
+	err = jerrors.Wrap(err, "wrap", kv("id", "outer"), kv("attempt", "2"))
+	Error(context.Background(), err, WithField("field", "value"))
+
+	require.Len(t, entries, 1)
+	eo := entries[0].ErrorObject
+	require.NotNil(t, eo)
+	assert.Equal(t, map[string]string{"id": "outer", "attempt": "2", "user": "alice"}, eo.Fields)
+	assert.Len(t, eo.Parameters, 4)
+
+	b, jerr := JSONFormatter{}.Format(entries[0])
+	require.NoError(t, jerr)
+	assert.Contains(t, string(b), `"fields":{"attempt":"2","id":"outer","user":"alice"}`)
+
+	SetErrorKVPlacement(ErrorKVPlacementRoot)
+	t.Cleanup(func() { SetErrorKVPlacement(ErrorKVPlacementBoth) })
+	Error(context.Background(), err)
+	require.Len(t, entries, 2)
+	assert.Nil(t, entries[1].ErrorObject.Fields)
+}
+
+func TestMaxValueLength(t *testing.T) {
+	SetMaxValueLength(8)
+	t.Cleanup(func() { SetMaxValueLength(0) })
+
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	ctx := ContextWith(context.Background(), kv("ctx", "0123456789"))
+	err := jerrors.New("error", kv("error", "0123456789"))
+	assert.Equal(t, map[string]string{"error": "01234567...[truncated 2 bytes]"}, jerrors.GetKeyValues(err))
+
+	Info(ctx, "info", WithField("field", "0123456789"), kv("short", "1"))
+	Error(ctx, err)
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, []models.KeyValue{
+		{Key: "ctx", Value: "01234567...[truncated 2 bytes]"},
+		{Key: "field", Value: "01234567...[truncated 2 bytes]"},
+		{Key: "short", Value: "1"},
+	}, entries[0].Parameters)
+	require.NotNil(t, entries[1].ErrorObject)
+	assert.Equal(t, []models.KeyValue{
+		{Key: "error", Value: "01234567...[truncated 2 bytes]"},
+	}, entries[1].ErrorObject.Parameters)
+}
+
+func TestErrorFingerprints(t *testing.T) {
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	ctx := context.Background()
+	err := jerrors.New("error", jerrors.WithCode("code"))
+	Error(ctx, err)
+
+	SetErrorFingerprints(true)
+	t.Cleanup(func() { SetErrorFingerprints(false) })
+	Error(ctx, err)
+	Info(ctx, "info")
+
+	require.Len(t, entries, 3)
+	assert.Empty(t, entries[0].ErrorFingerprint)
+	assert.Equal(t, jerrors.Fingerprint(err), entries[1].ErrorFingerprint)
+	assert.NotEmpty(t, entries[1].ErrorFingerprint)
+	assert.Empty(t, entries[2].ErrorFingerprint)
+}
+
+func TestMaxStackFrames(t *testing.T) {
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	frames := func(hop string) []string {
+		var ret []string
+		for i := 0; i < 20; i++ {
+			ret = append(ret, fmt.Sprintf("%s.go:%d", hop, i))
+		}
+		return ret
+	}
+	var err error
+	for _, hop := range []string{"origin", "middle", "top"} {
+		err = &internal.Error{Message: hop, Err: err, Binary: hop, StackTrace: frames(hop)}
+	}
+
+	ctx := context.Background()
+	Error(ctx, err)
+	SetMaxStackFrames(5)
+	t.Cleanup(func() { SetMaxStackFrames(0) })
+	Error(ctx, err)
+
+	require.Len(t, entries, 2)
+	full := entries[0].ErrorObject.StackTrace.Content()
+	assert.Len(t, full, 62)
+	assert.Equal(t, append(frames("origin")[:5], "... 57 more frames"),
+		entries[1].ErrorObject.StackTrace.Content())
+}
+
+func TestSourceFunc(t *testing.T) {
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	ctx := context.Background()
+	Info(ctx, "info")
+	Error(ctx, jerrors.New("error"))
+	func() {
+		Warn(ctx, "warn")
+	}()
+	Info(ctx, "set source", logOption(func(e *Entry) {
+		e.SetSource("other.go:1")
+	}))
+
+	assert.Equal(t, []string{
+		"github.com/peterlabuschagne/jettison/log.TestSourceFunc",
+		"github.com/peterlabuschagne/jettison/log.TestSourceFunc",
+		"github.com/peterlabuschagne/jettison/log.TestSourceFunc.func2",
+		"",
+	}, []string{entries[0].SourceFunc, entries[1].SourceFunc, entries[2].SourceFunc, entries[3].SourceFunc})
+}
+
+func TestErrorCycle(t *testing.T) {
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	// Modifying an error after wrapping it is the only way to create a cycle
+	a := &internal.Error{Message: "a", Code: "a"}
+	b := jerrors.Wrap(a, "b", jerrors.WithCode("b"))
+	a.Err = jerrors.Join(io.EOF, b)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Error(context.Background(), b)
+		Infof(context.Background(), "%+v", b)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("logging a cycle didn't terminate")
+	}
+	require.Len(t, entries, 2)
+}
@@ -1,17 +1,22 @@
 package log
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"io"
-	"log"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 // logger is the global logger. It defaults to a human friendly command line logger.
-var logger Logger = NewCmdLogger(os.Stderr, false)
+var logger atomic.Pointer[Logger]
+
+func init() {
+	SetLogger(NewCmdLogger(os.Stderr, false))
+}
 
 // Logger does logging of log lines.
 type Logger interface {
@@ -19,17 +24,115 @@ type Logger interface {
 	Log(context.Context, Entry) string
 }
 
-// SetLogger sets the global logger.
+// SetLogger sets the global logger. It is safe to call concurrently with
+// logging, subsequent logs are written to l.
 func SetLogger(l Logger) {
-	logger = l
+	logger.Store(&l)
+}
+
+// GetLogger returns the global logger.
+func GetLogger() Logger {
+	return *logger.Load()
+}
+
+// SetFormatter sets the global logger to one which writes log entries
+// formatted by f, one per line. Logs are written to the writer set by
+// SetWriter, or stderr if it hasn't been called.
+func SetFormatter(f Formatter) {
+	w := io.Writer(os.Stderr)
+	switch l := GetLogger().(type) {
+	case *formatLogger:
+		w = l.w
+	case *CmdLogger:
+		w = l.logger.Writer()
+	}
+	SetLogger(newFormatLogger(w, f))
+}
+
+// SetWriter sets the global logger to one which writes log entries to w,
+// in the same format as the current global logger, i.e. formatted by the
+// formatter set by SetFormatter, or by the command line logger if it hasn't
+// been called. Loggers set with SetLogger, other than those returned by
+// NewCmdLogger, are replaced by one which writes JSON, since their format
+// can't be kept. Each entry is written with a single call to w.Write, and
+// concurrent logs are never interleaved.
+func SetWriter(w io.Writer) {
+	switch l := GetLogger().(type) {
+	case *formatLogger:
+		SetLogger(newFormatLogger(w, l.formatter))
+	case *CmdLogger:
+		SetLogger(NewCmdLogger(w, l.stripTime))
+	default:
+		SetLogger(newFormatLogger(w, JSONFormatter{}))
+	}
+}
+
+// NopLogger is a Logger which discards logs.
+//
+//	log.SetLogger(log.NopLogger{})
+type NopLogger struct{}
+
+func (NopLogger) Log(context.Context, Entry) string {
+	return ""
+}
+
+// CaptureLogger is a Logger which keeps logs in memory, e.g. for tests to
+// assert on what was logged. It's safe to use concurrently.
+//
+//	l := log.NewCaptureLogger()
+//	log.SetLoggerForTesting(t, l)
+//	...
+//	assert.Equal(t, "done", l.Entries()[0].Message)
+//
+// Use Reset to clear the captured logs between test cases, or set a new
+// CaptureLogger for each test case.
+type CaptureLogger struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// NewCaptureLogger returns a CaptureLogger without any logs.
+func NewCaptureLogger() *CaptureLogger {
+	return new(CaptureLogger)
+}
+
+// Log keeps a copy of the log, it always returns an empty string.
+func (l *CaptureLogger) Log(_ context.Context, e Entry) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, e.Clone())
+	return ""
+}
+
+// Entries returns copies of the captured logs, in the order they were logged.
+func (l *CaptureLogger) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ret := make([]Entry, len(l.entries))
+	for i, e := range l.entries {
+		ret[i] = e.Clone()
+	}
+	return ret
+}
+
+// Reset clears the captured logs.
+func (l *CaptureLogger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
 }
 
+var (
+	_ Logger = NopLogger{}
+	_ Logger = (*CaptureLogger)(nil)
+)
+
 func SetLoggerForTesting(t testing.TB, l Logger) {
-	old := logger
+	old := logger.Load()
 	t.Cleanup(func() {
-		logger = old
+		logger.Store(old)
 	})
-	logger = l
+	SetLogger(l)
 }
 
 func SetCmdLoggerForTesting(t testing.TB, w io.Writer) {
@@ -43,37 +146,137 @@ func SetDefaultLoggerForTesting(t testing.TB, w io.Writer, opts ...Option) {
 	SetLoggerForTesting(t, l)
 }
 
-func newJSONLogger(w io.Writer, opts ...Option) *jsonLogger {
-	return &jsonLogger{
-		logger: log.New(w, "", 0),
-		opts:   opts,
+func newJSONLogger(w io.Writer, opts ...Option) *formatLogger {
+	return newFormatLogger(w, JSONFormatter{}, opts...)
+}
+
+func newFormatLogger(w io.Writer, f Formatter, opts ...Option) *formatLogger {
+	return &formatLogger{
+		w:         w,
+		formatter: f,
+		opts:      opts,
 	}
 }
 
-// jsonLogger is the default logger which writes json to stdout.
-type jsonLogger struct {
-	logger *log.Logger
+// formatLogger writes log entries formatted by a Formatter, one per line.
+type formatLogger struct {
+	mu        sync.Mutex
+	w         io.Writer
+	formatter Formatter
 
 	// default options and other flags for testing
 	opts           []Option
 	scrubTimestamp bool
 }
 
-func (jl *jsonLogger) Log(_ context.Context, l Entry) string {
-	for _, o := range jl.opts {
+// maxPooledBuffer is the largest buffer kept for reuse by formatLogger, so
+// that a single huge log doesn't pin its memory.
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+func (fl *formatLogger) Log(_ context.Context, l Entry) string {
+	l = fl.prepare(l)
+	sf, ok := fl.formatter.(StreamFormatter)
+	if !ok {
+		return fl.format(l)
+	}
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer putBuffer(buf)
+	if err := fl.stream(sf, buf, l); err != nil {
+		return fl.formatFailed(l, err)
+	}
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}))
+}
+
+// writeEntry is like Log, but doesn't return what was written, so that
+// entries written by a StreamFormatter are never copied out of the buffer.
+func (fl *formatLogger) writeEntry(_ context.Context, l Entry) {
+	l = fl.prepare(l)
+	sf, ok := fl.formatter.(StreamFormatter)
+	if !ok {
+		fl.format(l)
+		return
+	}
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer putBuffer(buf)
+	if err := fl.stream(sf, buf, l); err != nil {
+		fl.formatFailed(l, err)
+	}
+}
+
+// prepare applies the logger's options to l.
+func (fl *formatLogger) prepare(l Entry) Entry {
+	for _, o := range fl.opts {
 		o.ApplyToLog(&l)
 	}
-	if jl.scrubTimestamp {
+	if fl.scrubTimestamp {
 		l.Timestamp = time.Time{}
 	}
+	return l
+}
 
-	res, err := json.Marshal(l)
+// format writes l formatted by the logger's Formatter and returns it.
+func (fl *formatLogger) format(l Entry) string {
+	res, err := fl.formatter.Format(l)
 	if err != nil {
-		jl.logger.Printf("jettison/log: failed to marshal log: %v", err)
-		jl.logger.Print(l.Message) // best-effort
-		return l.Message
+		return fl.formatFailed(l, err)
 	}
 
-	jl.logger.Print(string(res))
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer putBuffer(buf)
+	buf.Write(res)
+	buf.WriteByte('\n')
+	fl.write(buf.Bytes())
 	return string(res)
 }
+
+// stream formats l with sf into buf, a buffer from bufferPool, and writes it.
+func (fl *formatLogger) stream(sf StreamFormatter, buf *bytes.Buffer, l Entry) error {
+	if err := sf.FormatTo(buf, l); err != nil {
+		return err
+	}
+	fl.write(buf.Bytes())
+	return nil
+}
+
+// entryWriter is implemented by loggers which can write an entry without
+// returning what was written, which saves copying large entries.
+type entryWriter interface {
+	writeEntry(ctx context.Context, e Entry)
+}
+
+// writeEntry writes e with l, without returning what was written if l
+// supports it, see entryWriter.
+func writeEntry(ctx context.Context, l Logger, e Entry) {
+	if w, ok := l.(entryWriter); ok {
+		w.writeEntry(ctx, e)
+		return
+	}
+	l.Log(ctx, e)
+}
+
+// formatFailed writes the message of l, since it couldn't be formatted.
+func (fl *formatLogger) formatFailed(l Entry, err error) string {
+	fl.write([]byte("jettison/log: failed to format log: " + err.Error() + "\n"))
+	fl.write([]byte(l.Message + "\n")) // best-effort
+	return l.Message
+}
+
+// write writes p with a single call to Write, so that concurrent logs are
+// never interleaved.
+func (fl *formatLogger) write(p []byte) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	_, _ = fl.w.Write(p)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
@@ -2,6 +2,8 @@ package log_test
 
 import (
 	"context"
+	"io"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -42,3 +44,66 @@ func toStr(l log.Entry) string {
 	}
 	return str
 }
+
+func TestGetLogger(t *testing.T) {
+	tl := new(testLogger)
+	t.Run("set for testing", func(t *testing.T) {
+		log.SetLoggerForTesting(t, tl)
+		assert.Equal(t, tl, log.GetLogger())
+	})
+	assert.NotEqual(t, tl, log.GetLogger())
+}
+
+func TestSetLoggerConcurrent(t *testing.T) {
+	log.SetLoggerForTesting(t, log.NewCmdLogger(io.Discard, true))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			log.SetLogger(log.NewCmdLogger(io.Discard, true))
+		}()
+		go func() {
+			defer wg.Done()
+			log.Info(context.Background(), "message")
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCaptureLogger(t *testing.T) {
+	l := log.NewCaptureLogger()
+	log.SetLoggerForTesting(t, l)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			log.Info(ctx, "info", j.KV("k", "v"))
+		}()
+	}
+	wg.Wait()
+	log.Error(ctx, errors.New("error"))
+
+	entries := l.Entries()
+	assert.Len(t, entries, 11)
+	assert.Equal(t, "error", entries[10].Message)
+
+	// Entries are copies
+	entries[0].Parameters[0].Value = "modified"
+	assert.Equal(t, "v", l.Entries()[0].Parameters[0].Value)
+
+	l.Reset()
+	assert.Empty(t, l.Entries())
+	log.Info(ctx, "after reset")
+	assert.Len(t, l.Entries(), 1)
+}
+
+func TestNopLogger(t *testing.T) {
+	log.SetLoggerForTesting(t, log.NopLogger{})
+	log.Info(context.Background(), "info")
+	log.Error(context.Background(), errors.New("error"))
+}
@@ -15,19 +15,93 @@ type ErrorObject struct {
 	Stack      []string           `json:"stack,omitempty"`
 	StackTrace ElasticStringArray `json:"stacktrace,omitempty"`
 	Parameters []models.KeyValue  `json:"parameters,omitempty"`
+	// Fields has the same key/values as Parameters as an object, for
+	// consumers which query error-specific data separately from the log's
+	// parameters. If a key is present more than once, the value from the most
+	// recently wrapped error is used, like errors.GetKeyValues.
+	Fields map[string]string `json:"fields,omitempty"`
+	// Timestamp is when the error was created, see SetErrorTimestamps
+	Timestamp *time.Time `json:"timestamp,omitempty"`
 }
 
 type Entry struct {
-	Message   string    `json:"message"`
-	Source    string    `json:"source"`
-	Level     Level     `json:"level"`
-	Timestamp time.Time `json:"timestamp"`
+	Message string `json:"message"`
+	Source  string `json:"source"`
+	// SourceFunc is the name of the function which wrote the log, including
+	// its package path, e.g. "github.com/org/repo/pkg.(*Type).Method"
+	SourceFunc string    `json:"source_func,omitempty"`
+	Level      Level     `json:"level"`
+	Timestamp  time.Time `json:"timestamp"`
+	// TraceID correlates the logs of a request across services,
+	// see WithTraceID
+	TraceID string `json:"trace_id,omitempty"`
+	// GoroutineID is the ID of the goroutine which wrote the log,
+	// see SetIncludeGoroutineID
+	GoroutineID uint64 `json:"goroutine_id,omitempty"`
 
 	Parameters []models.KeyValue `json:"parameters,omitempty"`
 	ErrorCode  *string           `json:"error_code,omitempty"`
+	// ErrorFingerprint identifies the logged error for grouping,
+	// see SetErrorFingerprints
+	ErrorFingerprint string `json:"error_fingerprint,omitempty"`
 
 	ErrorObject  *ErrorObject  `json:"error_object,omitempty"`
 	ErrorObjects []ErrorObject `json:"error_objects,omitempty"`
+
+	// sampleRate is set by WithSampleRate
+	sampleRate int
+}
+
+// Clone returns a deep copy of the entry, which can be modified without
+// affecting l.
+func (l Entry) Clone() Entry {
+	c := l
+	c.Parameters = cloneKeyValues(l.Parameters)
+	if l.ErrorCode != nil {
+		code := *l.ErrorCode
+		c.ErrorCode = &code
+	}
+	if l.ErrorObject != nil {
+		eo := l.ErrorObject.clone()
+		c.ErrorObject = &eo
+	}
+	if l.ErrorObjects != nil {
+		c.ErrorObjects = make([]ErrorObject, len(l.ErrorObjects))
+		for i, eo := range l.ErrorObjects {
+			c.ErrorObjects[i] = eo.clone()
+		}
+	}
+	return c
+}
+
+func (eo ErrorObject) clone() ErrorObject {
+	c := eo
+	c.Stack = append([]string(nil), eo.Stack...)
+	if eo.StackTrace != nil {
+		c.StackTrace = make(ElasticStringArray, len(eo.StackTrace))
+		for i, st := range eo.StackTrace {
+			c.StackTrace[i].Content = append([]string(nil), st.Content...)
+		}
+	}
+	c.Parameters = cloneKeyValues(eo.Parameters)
+	if eo.Fields != nil {
+		c.Fields = make(map[string]string, len(eo.Fields))
+		for k, v := range eo.Fields {
+			c.Fields[k] = v
+		}
+	}
+	if eo.Timestamp != nil {
+		ts := *eo.Timestamp
+		c.Timestamp = &ts
+	}
+	return c
+}
+
+func cloneKeyValues(kvs []models.KeyValue) []models.KeyValue {
+	if kvs == nil {
+		return nil
+	}
+	return append([]models.KeyValue(nil), kvs...)
 }
 
 // SetKey updates the list of parameters in the log with the given key/value pair.
@@ -42,11 +116,13 @@ func (l *Entry) SetKey(key, value string) {
 	})
 }
 
-// SetSource updates the source of the log.
+// SetSource updates the source of the log, clearing its SourceFunc since
+// the function at src isn't known.
 func (l *Entry) SetSource(src string) {
 	if l == nil {
 		return
 	}
 
 	l.Source = src
+	l.SourceFunc = ""
 }
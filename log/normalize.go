@@ -0,0 +1,64 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// NoMessage replaces the empty message of logs, e.g. log.Info(ctx, ""),
+// see SetStrictEntries.
+const NoMessage = "<no message>"
+
+var strictEntries atomic.Bool
+
+// SetStrictEntries sets how logs with an empty message or an invalid level
+// are handled. By default they are fixed, the message is replaced with
+// NoMessage and an invalid level with LevelInfo. When enabled, such logs are
+// dropped instead, and a warning with the same source is logged in their
+// place, so that the call writing them can be found and fixed. Logs of errors
+// are never dropped, they're fixed with the level set to LevelWarn instead.
+func SetStrictEntries(enabled bool) {
+	strictEntries.Store(enabled)
+}
+
+// normalLevel returns the level of logs written at l once normalized, so that
+// logs with an invalid level are filtered at the level they're written at.
+func normalLevel(l Level) Level {
+	if l.Valid() {
+		return l
+	}
+	if strictEntries.Load() {
+		return LevelWarn
+	}
+	return LevelInfo
+}
+
+// normalize fixes the message and level of e, or replaces it with a warning
+// if SetStrictEntries is enabled and e doesn't have an error.
+func normalize(e *Entry) {
+	var problems []string
+	if e.Message == "" {
+		problems = append(problems, "empty message")
+	}
+	if !e.Level.Valid() {
+		problems = append(problems, "invalid level "+strconv.Quote(string(e.Level)))
+	}
+	if len(problems) == 0 {
+		return
+	}
+	if !strictEntries.Load() || e.ErrorObject != nil || len(e.ErrorObjects) > 0 {
+		if e.Message == "" {
+			e.Message = NoMessage
+		}
+		e.Level = normalLevel(e.Level)
+		return
+	}
+	*e = Entry{
+		Message:    "jettison/log: dropped invalid log: " + strings.Join(problems, ", "),
+		Source:     e.Source,
+		SourceFunc: e.SourceFunc,
+		Level:      LevelWarn,
+		Timestamp:  e.Timestamp,
+	}
+}
@@ -0,0 +1,66 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/peterlabuschagne/jettison/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	setMinLevelForTesting(t, LevelInfo)
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	ctx := context.Background()
+	Info(ctx, "")
+	Info(ctx, "unknown level", WithLevel("verbose"))
+	Warn(ctx, "", WithLevel("verbose"))
+
+	require.Len(t, entries, 3)
+	assert.Equal(t, NoMessage, entries[0].Message)
+	assert.Equal(t, LevelInfo, entries[0].Level)
+	assert.Equal(t, "unknown level", entries[1].Message)
+	assert.Equal(t, LevelInfo, entries[1].Level)
+	assert.Equal(t, NoMessage, entries[2].Message)
+	assert.Equal(t, LevelInfo, entries[2].Level)
+}
+
+func TestStrictEntries(t *testing.T) {
+	SetStrictEntries(true)
+	t.Cleanup(func() { SetStrictEntries(false) })
+	setMinLevelForTesting(t, LevelWarn)
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	ctx := context.Background()
+	Warn(ctx, "", kv("key", "value"))
+	Info(ctx, "unknown level", WithLevel("verbose"))
+	Info(ctx, "", WithLevel("verbose"))
+	Warn(ctx, "valid")
+	Error(ctx, errors.New("", errors.WithCode("code")))
+
+	require.Len(t, entries, 5)
+	assert.Equal(t, "jettison/log: dropped invalid log: empty message", entries[0].Message)
+	assert.Empty(t, entries[0].Parameters)
+	assert.Equal(t, `jettison/log: dropped invalid log: invalid level "verbose"`, entries[1].Message)
+	assert.Equal(t, `jettison/log: dropped invalid log: empty message, invalid level "verbose"`, entries[2].Message)
+	for _, e := range entries[:3] {
+		assert.Equal(t, LevelWarn, e.Level)
+		assert.Equal(t, "github.com/peterlabuschagne/jettison/log.TestStrictEntries", e.SourceFunc)
+	}
+	assert.Equal(t, "valid", entries[3].Message)
+
+	// Logs of errors are kept
+	assert.Equal(t, NoMessage, entries[4].Message)
+	assert.Equal(t, LevelError, entries[4].Level)
+	require.NotNil(t, entries[4].ErrorObject)
+	require.NotNil(t, entries[4].ErrorCode)
+	assert.Equal(t, "code", *entries[4].ErrorCode)
+}
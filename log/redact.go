@@ -0,0 +1,66 @@
+package log
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/peterlabuschagne/jettison/models"
+)
+
+// RedactedValue replaces the value of redacted parameters.
+const RedactedValue = "REDACTED"
+
+// RedactFunc returns true if the value of the parameter with the given key
+// should be redacted.
+type RedactFunc func(key string) bool
+
+var redactFunc atomic.Pointer[RedactFunc]
+
+// SetRedactedKeys sets the keys of parameters which have their value replaced
+// with RedactedValue before being logged. Keys are matched case-insensitively.
+// This replaces any previously set keys or RedactFunc.
+func SetRedactedKeys(keys ...string) {
+	if len(keys) == 0 {
+		SetRedactFunc(nil)
+		return
+	}
+	redacted := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		redacted[strings.ToLower(k)] = true
+	}
+	SetRedactFunc(func(key string) bool {
+		return redacted[strings.ToLower(key)]
+	})
+}
+
+// SetRedactFunc sets a function to select parameters which have their value
+// replaced with RedactedValue before being logged, for rules more complex
+// than those supported by SetRedactedKeys. This replaces any previously set
+// keys or RedactFunc, a nil f disables redaction.
+func SetRedactFunc(f RedactFunc) {
+	redactFunc.Store(&f)
+}
+
+// redact replaces the values of redacted parameters in the entry,
+// including the parameters of any errors.
+func redact(e *Entry) {
+	f := redactFunc.Load()
+	if f == nil || *f == nil {
+		return
+	}
+	redactParams(e.Parameters, *f)
+	if e.ErrorObject != nil {
+		redactParams(e.ErrorObject.Parameters, *f)
+	}
+	for _, eo := range e.ErrorObjects {
+		redactParams(eo.Parameters, *f)
+	}
+}
+
+func redactParams(params []models.KeyValue, f RedactFunc) {
+	for i := range params {
+		if f(params[i].Key) {
+			params[i] = models.KeyValue{Key: params[i].Key, Value: RedactedValue}
+		}
+	}
+}
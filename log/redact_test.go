@@ -0,0 +1,77 @@
+package log
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	jerrors "github.com/peterlabuschagne/jettison/errors"
+	"github.com/peterlabuschagne/jettison/models"
+)
+
+func setRedactFuncForTesting(t *testing.T) {
+	t.Cleanup(func() {
+		SetRedactFunc(nil)
+	})
+}
+
+func TestRedactedKeys(t *testing.T) {
+	setRedactFuncForTesting(t)
+	SetRedactedKeys("Password", "token")
+
+	var e Entry
+	SetLoggerForTesting(t, loggerFunc(func(entry Entry) {
+		e = entry
+	}))
+	ctx := ContextWith(context.Background(), kv("TOKEN", "ctx_secret"))
+	err := jerrors.New("test", jerrors.WithKV("password", "err_secret"))
+	Error(ctx, err, kv("user", "bob"))
+
+	assert.Equal(t, []models.KeyValue{
+		{Key: "TOKEN", Value: RedactedValue},
+		{Key: "password", Value: RedactedValue},
+		{Key: "user", Value: "bob"},
+	}, e.Parameters)
+	assert.Equal(t, []models.KeyValue{
+		{Key: "password", Value: RedactedValue},
+	}, e.ErrorObject.Parameters)
+
+	// The error itself is not modified
+	assert.Equal(t, map[string]string{"password": "err_secret"}, jerrors.GetKeyValues(err))
+}
+
+func TestRedactFunc(t *testing.T) {
+	setRedactFuncForTesting(t)
+	re := regexp.MustCompile("_secret$")
+	SetRedactFunc(re.MatchString)
+
+	var e Entry
+	SetLoggerForTesting(t, loggerFunc(func(entry Entry) {
+		e = entry
+	}))
+	Info(context.Background(), "msg",
+		kv("api_secret", "abc"),
+		kv("secret_santa", "bob"),
+	)
+
+	assert.Equal(t, []models.KeyValue{
+		{Key: "api_secret", Value: RedactedValue},
+		{Key: "secret_santa", Value: "bob"},
+	}, e.Parameters)
+}
+
+func TestRedactDisabled(t *testing.T) {
+	setRedactFuncForTesting(t)
+	SetRedactedKeys("token")
+	SetRedactedKeys()
+
+	var e Entry
+	SetLoggerForTesting(t, loggerFunc(func(entry Entry) {
+		e = entry
+	}))
+	Info(context.Background(), "msg", kv("token", "abc"))
+
+	assert.Equal(t, []models.KeyValue{{Key: "token", Value: "abc"}}, e.Parameters)
+}
@@ -0,0 +1,83 @@
+package log
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+)
+
+// SampledCountKey is the parameter added to sampled logs with the number of
+// logs which were dropped since the last one was written.
+const SampledCountKey = "sampled_count"
+
+// WithSampleRate returns a jettison option to only write 1 in every n logs
+// with the same message, the others are dropped. The first log for a message
+// is always written. When a log is written after some were dropped,
+// the number dropped is added as the SampledCountKey parameter. Only the
+// most recently logged messages are tracked, so the first log of a message
+// which has been forgotten is written again.
+// It only works when provided as option to log package functions.
+func WithSampleRate(n int) Option {
+	return logOption(func(e *Entry) {
+		e.sampleRate = n
+	})
+}
+
+// sampleCapacity is the maximum number of distinct messages tracked for
+// sampling, the least recently logged are forgotten first.
+const sampleCapacity = 1024
+
+type sampleState struct {
+	message string
+	dropped int
+}
+
+var samplers = struct {
+	sync.Mutex
+	// lru is ordered most recently logged first, each element has the
+	// number of logs dropped for its message since the last one was written
+	lru      *list.List
+	messages map[string]*list.Element
+}{lru: list.New(), messages: make(map[string]*list.Element)}
+
+// ResetSamplers clears the sampling state of all messages, so that the next
+// log for each message will be written.
+func ResetSamplers() {
+	samplers.Lock()
+	defer samplers.Unlock()
+	samplers.lru.Init()
+	samplers.messages = make(map[string]*list.Element)
+}
+
+// sample returns true if the entry should be written,
+// adding the number of dropped logs to its parameters.
+func sample(e *Entry) bool {
+	if e.sampleRate <= 1 {
+		return true
+	}
+	samplers.Lock()
+	defer samplers.Unlock()
+
+	el, ok := samplers.messages[e.Message]
+	if !ok {
+		el = samplers.lru.PushFront(&sampleState{message: e.Message})
+		samplers.messages[e.Message] = el
+		if samplers.lru.Len() > sampleCapacity {
+			oldest := samplers.lru.Remove(samplers.lru.Back()).(*sampleState)
+			delete(samplers.messages, oldest.message)
+		}
+		return true
+	}
+	samplers.lru.MoveToFront(el)
+
+	s := el.Value.(*sampleState)
+	if s.dropped < e.sampleRate-1 {
+		s.dropped++
+		return false
+	}
+	if s.dropped > 0 {
+		e.SetKey(SampledCountKey, strconv.Itoa(s.dropped))
+	}
+	s.dropped = 0
+	return true
+}
@@ -0,0 +1,73 @@
+package log
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/peterlabuschagne/jettison/models"
+)
+
+func TestWithSampleRate(t *testing.T) {
+	t.Cleanup(ResetSamplers)
+
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+	ctx := context.Background()
+	for i := 0; i < 7; i++ {
+		Info(ctx, "hot path", WithSampleRate(3), kv("i", i))
+		Info(ctx, "other path", WithSampleRate(5))
+	}
+	Info(ctx, "not sampled")
+
+	var act []string
+	for _, e := range entries {
+		act = append(act, e.Message+parameterString(e.Parameters))
+	}
+	assert.Equal(t, []string{
+		"hot path[i=0]",
+		"other path",
+		"hot path[i=3,sampled_count=2]",
+		"other path[sampled_count=4]",
+		"hot path[i=6,sampled_count=2]",
+		"not sampled",
+	}, act)
+}
+
+func TestResetSamplers(t *testing.T) {
+	t.Cleanup(ResetSamplers)
+
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+	ctx := context.Background()
+	Info(ctx, "msg", WithSampleRate(10))
+	Info(ctx, "msg", WithSampleRate(10))
+	ResetSamplers()
+	Info(ctx, "msg", WithSampleRate(10))
+
+	assert.Len(t, entries, 2)
+	assert.Equal(t, []models.KeyValue(nil), entries[1].Parameters)
+}
+
+func TestSampleCapacity(t *testing.T) {
+	t.Cleanup(ResetSamplers)
+
+	var n int
+	SetLoggerForTesting(t, loggerFunc(func(Entry) { n++ }))
+
+	ctx := context.Background()
+	Info(ctx, "first", WithSampleRate(10))
+	for i := 0; i < sampleCapacity; i++ {
+		Info(ctx, strconv.Itoa(i), WithSampleRate(10))
+	}
+	assert.Equal(t, sampleCapacity, samplers.lru.Len())
+	// first has been forgotten
+	Info(ctx, "first", WithSampleRate(10))
+	assert.Equal(t, sampleCapacity+2, n)
+}
@@ -28,3 +28,12 @@ func TestSourceError(t *testing.T) {
 	log.Error(nil, errors.New("test error"))
 	goldie.New(t).Assert(t, "source_error", trace.StripTestStacks(t, buf.Bytes()))
 }
+
+// TestSourceErrorTrimmed tests the stack trace with the module path trimmed.
+func TestSourceErrorTrimmed(t *testing.T) {
+	trace.SetTrimPrefixTesting(t, "github.com/peterlabuschagne/jettison/")
+	buf := new(bytes.Buffer)
+	log.SetDefaultLoggerForTesting(t, buf)
+	log.Error(nil, errors.New("test error"))
+	goldie.New(t).Assert(t, "source_error_trimmed", buf.Bytes())
+}
@@ -0,0 +1,107 @@
+package log
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/peterlabuschagne/jettison/models"
+)
+
+// With returns a logger which applies the given options to every log, before
+// any options passed when logging. Parameters passed when logging replace
+// bound parameters with the same key. Context key/value pairs are still
+// included in the logs.
+//
+//	l := log.With(log.WithField("service", "payments"))
+//	l.Info(ctx, "payment received", log.WithField("amount", amount))
+func With(opts ...Option) Interface {
+	return boundLogger{opts: opts}
+}
+
+type boundLogger struct {
+	opts []Option
+}
+
+func (b boundLogger) Debug(ctx context.Context, msg string, ol ...Option) {
+	Debug(ctx, msg, b.bind(ol))
+}
+
+func (b boundLogger) Info(ctx context.Context, msg string, ol ...Option) {
+	Info(ctx, msg, b.bind(ol))
+}
+
+func (b boundLogger) Warn(ctx context.Context, msg string, ol ...Option) {
+	Warn(ctx, msg, b.bind(ol))
+}
+
+func (b boundLogger) Error(ctx context.Context, err error, ol ...Option) {
+	Error(ctx, err, b.bind(ol))
+}
+
+func (b boundLogger) Debugf(ctx context.Context, format string, args ...any) {
+	Debug(ctx, fmt.Sprintf(format, args...), b.bind([]Option{withFormat(format, args)}))
+}
+
+func (b boundLogger) Infof(ctx context.Context, format string, args ...any) {
+	Info(ctx, fmt.Sprintf(format, args...), b.bind([]Option{withFormat(format, args)}))
+}
+
+func (b boundLogger) Warnf(ctx context.Context, format string, args ...any) {
+	Warn(ctx, fmt.Sprintf(format, args...), b.bind([]Option{withFormat(format, args)}))
+}
+
+func (b boundLogger) Errorf(ctx context.Context, err error, format string, args ...any) {
+	msg := withMessage(fmt.Sprintf(format, args...))
+	Error(ctx, err, b.bind([]Option{withFormat(format, args), msg}))
+}
+
+func (b boundLogger) Flush(ctx context.Context) error {
+	return Flush(ctx)
+}
+
+// bind returns a single option applying the bound options and then ol,
+// with the source set to the caller of the boundLogger method.
+func (b boundLogger) bind(ol []Option) Option {
+	src, fn := callerSource(2)
+	return boundOption{level: logLevel(logLevel("", b.opts), ol), apply: func(e *Entry) {
+		e.Source, e.SourceFunc = src, fn
+
+		n := len(e.Parameters)
+		for _, o := range b.opts {
+			o.ApplyToLog(e)
+		}
+		bound := make([]models.KeyValue, len(e.Parameters)-n)
+		copy(bound, e.Parameters[n:])
+		e.Parameters = e.Parameters[:n]
+
+		for _, o := range ol {
+			o.ApplyToLog(e)
+		}
+		override := make(map[string]bool)
+		for _, kv := range e.Parameters[n:] {
+			override[kv.Key] = true
+		}
+		for _, kv := range bound {
+			if !override[kv.Key] {
+				e.Parameters = append(e.Parameters, kv)
+			}
+		}
+	}}
+}
+
+// boundOption is the option returned by bind, which keeps the level set by
+// the options so that the log can be filtered before they're applied.
+type boundOption struct {
+	level Level
+	apply logOption
+}
+
+func (o boundOption) ApplyToLog(e *Entry) {
+	o.apply(e)
+}
+
+func (o boundOption) logLevel() (Level, bool) {
+	return o.level, o.level != ""
+}
+
+var _ Interface = boundLogger{}
@@ -0,0 +1,68 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/peterlabuschagne/jettison/errors"
+	"github.com/peterlabuschagne/jettison/models"
+)
+
+func TestWith(t *testing.T) {
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	l := With(WithField("service", "payments"), WithField("region", "eu"))
+	ctx := ContextWith(context.Background(), kv("request", "abc"))
+
+	l.Info(ctx, "info", WithField("region", "us"))
+	l.Warn(ctx, "warn")
+	l.Error(nil, errors.New("error"), WithField("extra", 1))
+
+	assert.Len(t, entries, 3)
+	assert.Equal(t, []models.KeyValue{
+		{Key: "region", Value: "us"},
+		{Key: "request", Value: "abc"},
+		{Key: "service", Value: "payments"},
+	}, entries[0].Parameters)
+	assert.Equal(t, LevelInfo, entries[0].Level)
+
+	assert.Equal(t, []models.KeyValue{
+		{Key: "region", Value: "eu"},
+		{Key: "request", Value: "abc"},
+		{Key: "service", Value: "payments"},
+	}, entries[1].Parameters)
+	assert.Equal(t, LevelWarn, entries[1].Level)
+
+	assert.Equal(t, []models.KeyValue{
+		{Key: "extra", Value: "1"},
+		{Key: "region", Value: "eu"},
+		{Key: "service", Value: "payments"},
+	}, entries[2].Parameters)
+	assert.Equal(t, LevelError, entries[2].Level)
+	assert.NotNil(t, entries[2].ErrorObject)
+
+	for _, e := range entries {
+		// The source is the call to the bound logger
+		assert.Contains(t, e.Source, "log/with_test.go:")
+		assert.Equal(t, "github.com/peterlabuschagne/jettison/log.TestWith", e.SourceFunc)
+	}
+}
+
+func TestWithLevelOverride(t *testing.T) {
+	var entries []Entry
+	SetLoggerForTesting(t, loggerFunc(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	l := With(WithLevel(LevelWarn))
+	l.Info(context.Background(), "bound level")
+	l.Info(context.Background(), "call-site level", WithLevel(LevelError))
+
+	assert.Equal(t, LevelWarn, entries[0].Level)
+	assert.Equal(t, LevelError, entries[1].Level)
+}
@@ -2,7 +2,136 @@
 // to loggers.
 package models
 
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// KeyValue is a key/value pair of a log or error. Since it has more than the
+// Key and Value fields, literals must name their fields, i.e.
+// models.KeyValue{Key: "k", Value: "v"} rather than models.KeyValue{"k", "v"}.
 type KeyValue struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
+	// Type is the type of Value when written as JSON, it defaults to a
+	// JSON string. It's not sent over gRPC.
+	Type ValueType `json:"-"`
+
+	// lazy computes Value when the key/value is resolved, see Lazy.
+	lazy *lazyValue
+}
+
+type lazyValue struct {
+	once  sync.Once
+	fn    func() string
+	value string
+}
+
+// Lazy returns a key/value whose value is computed by fn when it's resolved,
+// i.e. when it's logged or sent, rather than when it's created. fn is called
+// at most once, and must be safe to call from any goroutine.
+// The Value of the key/value is empty until it's resolved with Resolve.
+func Lazy(key string, fn func() string) KeyValue {
+	return KeyValue{Key: key, lazy: &lazyValue{fn: fn}}
+}
+
+// Resolve returns the key/value with its value computed if it was created by
+// Lazy, otherwise it returns kv.
+func (kv KeyValue) Resolve() KeyValue {
+	if kv.lazy == nil {
+		return kv
+	}
+	l := kv.lazy
+	l.once.Do(func() {
+		l.value = l.fn()
+	})
+	return KeyValue{Key: kv.Key, Value: l.value, Type: kv.Type}
+}
+
+// ResolveAll returns kvs with the values of any key/values created by Lazy
+// computed. kvs is returned if none of them were created by Lazy, otherwise
+// a copy is returned.
+func ResolveAll(kvs []KeyValue) []KeyValue {
+	for i, kv := range kvs {
+		if kv.lazy == nil {
+			continue
+		}
+		ret := make([]KeyValue, len(kvs))
+		copy(ret, kvs)
+		for j := i; j < len(ret); j++ {
+			ret[j] = ret[j].Resolve()
+		}
+		return ret
+	}
+	return kvs
+}
+
+// ValueType describes how the Value of a KeyValue is written as JSON.
+type ValueType string
+
+const (
+	TypeString ValueType = ""
+	TypeInt    ValueType = "int"
+	TypeFloat  ValueType = "float"
+	TypeBool   ValueType = "bool"
+)
+
+type jsonKeyValue struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// MarshalJSON writes the value as a JSON number or boolean if it has that
+// Type, otherwise as a JSON string. Values which aren't valid for their
+// Type are written as strings.
+func (kv KeyValue) MarshalJSON() ([]byte, error) {
+	kv = kv.Resolve()
+	var native bool
+	switch kv.Type {
+	case TypeInt:
+		_, err := strconv.ParseInt(kv.Value, 10, 64)
+		native = err == nil
+	case TypeFloat:
+		f, err := strconv.ParseFloat(kv.Value, 64)
+		native = err == nil && !math.IsInf(f, 0) && !math.IsNaN(f)
+	case TypeBool:
+		native = kv.Value == "true" || kv.Value == "false"
+	}
+	v := []byte(kv.Value)
+	if !native {
+		var err error
+		v, err = json.Marshal(kv.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(jsonKeyValue{Key: kv.Key, Value: v})
+}
+
+// UnmarshalJSON reads values written by MarshalJSON, setting the Type of
+// JSON numbers and booleans.
+func (kv *KeyValue) UnmarshalJSON(b []byte) error {
+	var j jsonKeyValue
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*kv = KeyValue{Key: j.Key}
+	v := string(j.Value)
+	switch {
+	case v == "" || v == "null":
+	case v == "true" || v == "false":
+		kv.Value, kv.Type = v, TypeBool
+	case v[0] == '"':
+		return json.Unmarshal(j.Value, &kv.Value)
+	default:
+		kv.Value = v
+		if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+			kv.Type = TypeInt
+		} else if _, err := strconv.ParseFloat(v, 64); err == nil {
+			kv.Type = TypeFloat
+		}
+	}
+	return nil
 }
@@ -41,22 +41,26 @@ func ExampleError() {
 
 func ExampleKS() {
 	err := errors.New("using j.KS",
-		j.KS("string_key", "value"))
+		j.KS("string_key", "value"), errors.WithoutStackTrace())
 
 	fmt.Printf("%%+v: %+v\n", err)
 	fmt.Printf("%%#v: %#v\n", err)
 	// Output:
-	// %+v: using j.KS(string_key=value)
+	// %+v: using j.KS
+	//   source: github.com/peterlabuschagne/jettison/readme/readme_test.go:43
+	//   string_key=value
 	// %#v: using j.KS(string_key=value)
 }
 
 func ExampleKV() {
 	err := errors.New("using j.KV",
-		j.KV("int_key", 1))
+		j.KV("int_key", 1), errors.WithoutStackTrace())
 
 	fmt.Printf("%%+v: %+v\n", err)
 	fmt.Printf("%%#v: %#v\n", err)
 	// Output:
-	// %+v: using j.KV(int_key=1)
+	// %+v: using j.KV
+	//   source: github.com/peterlabuschagne/jettison/readme/readme_test.go:56
+	//   int_key=1
 	// %#v: using j.KV(int_key=1)
 }
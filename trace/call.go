@@ -0,0 +1,102 @@
+package trace
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// The functions in this file format runtime frames the same way go-stack
+// formats a stack.Call, which can only be created by go-stack itself, so
+// that stack traces can be collected without go-stack's allocations.
+
+// runtimePath is the GOROOT source directory, e.g. "/usr/local/go/src/"
+var runtimePath = func() string {
+	var pcs [1]uintptr
+	runtime.Callers(0, pcs[:])
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	// frame.File is runtime.Callers' file, i.e. <runtimePath>runtime/extern.go
+	file := frame.File
+	dir := strings.LastIndex(file, "/")
+	if dir < 0 {
+		return ""
+	}
+	path := file[:strings.LastIndex(file[:dir], "/")+1]
+	if runtime.GOOS == "windows" {
+		path = strings.ToLower(path)
+	}
+	return path
+}()
+
+// inGoroot returns true for frames from unknown files, files under GOROOT,
+// or _testmain.go, like stack.CallStack.TrimRuntime.
+func inGoroot(frame runtime.Frame) bool {
+	file := frame.File
+	if len(file) == 0 || file[0] == '?' {
+		return true
+	}
+	if runtime.GOOS == "windows" {
+		file = strings.ToLower(file)
+	}
+	return strings.HasPrefix(file, runtimePath) || strings.HasSuffix(file, "/_testmain.go")
+}
+
+// trimRuntime removes the topmost frames from the Go runtime.
+func trimRuntime(frames []runtime.Frame) []runtime.Frame {
+	for len(frames) > 0 && inGoroot(frames[len(frames)-1]) {
+		frames = frames[:len(frames)-1]
+	}
+	return frames
+}
+
+// funcName returns the name of the frame's function without its package,
+// like the %n verb.
+func funcName(frame runtime.Frame) string {
+	name := frame.Function
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "."); i != -1 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// pkgPath returns the path of the frame's package, like the %+k verb.
+func pkgPath(frame runtime.Frame) string {
+	name := frame.Function
+	start := 0
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		start = i + 1
+	}
+	if i := strings.Index(name[start:], "."); i != -1 {
+		return name[:start+i]
+	}
+	return name
+}
+
+// fileRef returns the frame's file, qualified by its package path, and line,
+// like the %+v verb.
+func fileRef(frame runtime.Frame) string {
+	if frame == (runtime.Frame{}) {
+		return "%!v(NOFUNC)"
+	}
+	file := frame.File
+	if i := strings.LastIndex(file, "/"); i != -1 {
+		// Keep the file's directory, which may differ from the package name
+		file = file[strings.LastIndex(file[:i], "/")+1:]
+	}
+	if i := strings.LastIndex(frame.Function, "/"); i != -1 {
+		file = frame.Function[:i] + "/" + file
+	}
+	return file + ":" + strconv.Itoa(frame.Line)
+}
+
+// stackLine returns the default stack trace line for the frame, like the
+// format "%+v %n".
+func stackLine(frame runtime.Frame) string {
+	if frame == (runtime.Frame{}) {
+		return "%!v(NOFUNC) %!n(NOFUNC)"
+	}
+	return fileRef(frame) + " " + funcName(frame)
+}
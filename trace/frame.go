@@ -0,0 +1,80 @@
+package trace
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/peterlabuschagne/jettison/internal"
+)
+
+// Frame is a parsed stack trace frame.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+	// Binary is the binary which produced the stack trace
+	Binary string
+}
+
+// Frames returns the filtered frames of the traces, in the same order as
+// FullTrace, with the binary each frame came from instead of markers.
+// Frames are parsed from the default "file:line function" format, frames in
+// other formats are returned with only File set.
+func (m *Merge) Frames() []Frame {
+	var ret []Frame
+	traces := m.filtered()
+	for i := len(traces) - 1; i >= 0; i-- {
+		for _, f := range traces[i] {
+			frame := parseFrame(f)
+			frame.Binary = m.binaries[i]
+			ret = append(ret, frame)
+		}
+	}
+	return ret
+}
+
+// Frames returns the merged frames of the stack traces in a jettison error
+// chain, as logged by the log package. Only the first of any joined errors
+// is followed, and the chain is only followed until it reaches an error
+// already in it.
+func Frames(err error) []Frame {
+	var (
+		m    Merge
+		path internal.Path
+	)
+	for err != nil {
+		var ok bool
+		if path, ok = path.Visit(err); !ok {
+			break
+		}
+		if je, ok := err.(*internal.Error); ok && len(je.StackTrace) > 0 {
+			m.Add(je.StackTrace, je.Binary)
+		}
+		switch unw := err.(type) {
+		case interface{ Unwrap() error }:
+			err = unw.Unwrap()
+		case interface{ Unwrap() []error }:
+			errs := unw.Unwrap()
+			if len(errs) == 0 {
+				return m.Frames()
+			}
+			err = errs[0]
+		default:
+			err = nil
+		}
+	}
+	return m.Frames()
+}
+
+func parseFrame(s string) Frame {
+	ref, fn, _ := strings.Cut(s, " ")
+	i := strings.LastIndex(ref, ":")
+	if i < 0 {
+		return Frame{File: s}
+	}
+	line, err := strconv.Atoi(ref[i+1:])
+	if err != nil {
+		return Frame{File: s}
+	}
+	return Frame{Function: fn, File: ref[:i], Line: line}
+}
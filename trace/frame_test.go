@@ -0,0 +1,88 @@
+package trace
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/peterlabuschagne/jettison/internal"
+)
+
+func TestParseFrame(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expFrame Frame
+	}{
+		{
+			in:       "github.com/a/b/c.go:42 (*T).Method",
+			expFrame: Frame{Function: "(*T).Method", File: "github.com/a/b/c.go", Line: 42},
+		},
+		{
+			in:       "c.go:1",
+			expFrame: Frame{File: "c.go", Line: 1},
+		},
+		{
+			in:       "custom format",
+			expFrame: Frame{File: "custom format"},
+		},
+		{
+			in:       "c.go:X tRunner",
+			expFrame: Frame{File: "c.go:X tRunner"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			assert.Equal(t, tc.expFrame, parseFrame(tc.in))
+		})
+	}
+}
+
+func TestFrames(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		expFrames []Frame
+	}{
+		{name: "nil"},
+		{name: "non-jettison", err: io.EOF},
+		{
+			name: "single binary",
+			err: &internal.Error{
+				Message:    "outer",
+				Binary:     "client",
+				StackTrace: []string{"client/main.go:10 main", "runtime/proc.go:250 main"},
+				Err:        io.EOF,
+			},
+			expFrames: []Frame{
+				{Function: "main", File: "client/main.go", Line: 10, Binary: "client"},
+			},
+		},
+		{
+			name: "multiple binaries",
+			err: &internal.Error{
+				Message:    "client",
+				Binary:     "client",
+				StackTrace: []string{"client/main.go:10 main"},
+				Err: &internal.Error{
+					Message: "no trace",
+					Err: &internal.Error{
+						Message:    "server",
+						Binary:     "server",
+						StackTrace: []string{"server/handler.go:20 Handle", "server/db.go:30 Query"},
+					},
+				},
+			},
+			expFrames: []Frame{
+				{Function: "Handle", File: "server/handler.go", Line: 20, Binary: "server"},
+				{Function: "Query", File: "server/db.go", Line: 30, Binary: "server"},
+				{Function: "main", File: "client/main.go", Line: 10, Binary: "client"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expFrames, Frames(tc.err))
+		})
+	}
+}
@@ -1,8 +1,36 @@
 package trace
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+// FilterFunc reports whether a stack trace frame should be dropped from a
+// merged trace.
+type FilterFunc func(frame string) bool
+
+// PrefixFilter drops frames whose source reference starts with any of the
+// given path prefixes, e.g. "runtime/" or "google.golang.org/grpc/".
+func PrefixFilter(prefixes ...string) FilterFunc {
+	return func(frame string) bool {
+		for _, p := range prefixes {
+			if strings.HasPrefix(frame, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// DefaultFilters are used by Merge when no Filters are set.
+// They remove frames from the Go runtime and the testing package.
+var DefaultFilters = []FilterFunc{PrefixFilter("runtime/", "testing/")}
 
 type Merge struct {
+	// Filters drop frames from each trace before they are merged.
+	// If nil, DefaultFilters are used, set to an empty slice to keep all frames.
+	Filters []FilterFunc
+
 	traces   [][]string
 	binaries []string
 }
@@ -12,10 +40,19 @@ func (m *Merge) Add(trace []string, binary string) {
 	m.binaries = append(m.binaries, binary)
 }
 
+// FullTrace returns the filtered traces, in reverse order of calls to Add,
+// separated by markers showing which binary each trace came from.
+// Frames are trimmed using the prefix set with SetTrimPrefix.
+//
+// Frames at the start of a trace which repeat the frames at the end of the
+// trace before it are removed, e.g. when binaries built from the same code
+// share a utility function at the boundary between them. Repeated frames
+// within a trace, e.g. from recursion, are kept.
 func (m *Merge) FullTrace() []string {
 	var ret []string
-	for i := len(m.traces) - 1; i >= 0; i-- {
-		ret = append(ret, m.traces[i]...)
+	traces := m.filtered()
+	for i := len(traces) - 1; i >= 0; i-- {
+		ret = append(ret, traces[i]...)
 		if i > 0 {
 			ret = append(ret,
 				fmt.Sprintf("%s -> %s", m.binaries[i-1], m.binaries[i]),
@@ -24,3 +61,65 @@ func (m *Merge) FullTrace() []string {
 	}
 	return ret
 }
+
+// filtered returns the filtered traces, in the same order as they were
+// added, with frames repeated at the boundaries between them removed.
+func (m *Merge) filtered() [][]string {
+	ret := make([][]string, len(m.traces))
+	var prev []string
+	for i := len(m.traces) - 1; i >= 0; i-- {
+		trace := m.filter(m.traces[i])
+		ret[i] = trace[boundaryOverlap(prev, trace):]
+		if len(ret[i]) > 0 {
+			prev = ret[i]
+		}
+	}
+	return ret
+}
+
+// boundaryOverlap returns the length of the longest run of frames at the
+// start of next which repeats the frames at the end of prev.
+func boundaryOverlap(prev, next []string) int {
+	n := len(prev)
+	if len(next) < n {
+		n = len(next)
+	}
+	for ; n > 0; n-- {
+		if equalFrames(prev[len(prev)-n:], next[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+func equalFrames(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Merge) filter(trace []string) []string {
+	filters := m.Filters
+	if filters == nil {
+		filters = DefaultFilters
+	}
+	var ret []string
+	for _, frame := range trace {
+		if !dropFrame(filters, frame) {
+			ret = append(ret, trimFrame(frame))
+		}
+	}
+	return ret
+}
+
+func dropFrame(filters []FilterFunc, frame string) bool {
+	for _, f := range filters {
+		if f(frame) {
+			return true
+		}
+	}
+	return false
+}
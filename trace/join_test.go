@@ -55,6 +55,33 @@ func TestMerge(t *testing.T) {
 				"from_a",
 			},
 		},
+		{
+			name: "frames repeated at boundary are removed",
+			traces: []trace{
+				{trace: []string{"util.go:5 Do", "client.go:10 Call"}, binary: "a"},
+				{trace: []string{"server.go:20 Handle", "util.go:5 Do"}, binary: "b"},
+			},
+			expFullTrace: []string{
+				"server.go:20 Handle",
+				"util.go:5 Do",
+				"a -> b",
+				"client.go:10 Call",
+			},
+		},
+		{
+			name: "recursion within a trace is kept",
+			traces: []trace{
+				{trace: []string{"walk.go:3 walk", "main.go:1 main"}, binary: "a"},
+				{trace: []string{"walk.go:3 walk", "walk.go:3 walk", "walk.go:3 walk"}, binary: "b"},
+			},
+			expFullTrace: []string{
+				"walk.go:3 walk",
+				"walk.go:3 walk",
+				"walk.go:3 walk",
+				"a -> b",
+				"main.go:1 main",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -67,3 +94,66 @@ func TestMerge(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeFilters(t *testing.T) {
+	testCases := []struct {
+		name         string
+		filters      []FilterFunc
+		expFullTrace []string
+	}{
+		{
+			name: "default filters",
+			expFullTrace: []string{
+				"service/b.go:20 B",
+				"a -> b",
+				"service/a.go:10 A",
+				"google.golang.org/grpc/server.go:5 handle",
+			},
+		},
+		{
+			name:    "no filters",
+			filters: []FilterFunc{},
+			expFullTrace: []string{
+				"service/b.go:20 B",
+				"testing/testing.go:1 tRunner",
+				"a -> b",
+				"service/a.go:10 A",
+				"google.golang.org/grpc/server.go:5 handle",
+				"runtime/asm_amd64.s:1 goexit",
+			},
+		},
+		{
+			name:    "custom prefixes",
+			filters: []FilterFunc{PrefixFilter("google.golang.org/grpc/", "service/b")},
+			expFullTrace: []string{
+				"testing/testing.go:1 tRunner",
+				"a -> b",
+				"service/a.go:10 A",
+				"runtime/asm_amd64.s:1 goexit",
+			},
+		},
+		{
+			name:    "everything filtered keeps binary markers",
+			filters: []FilterFunc{func(string) bool { return true }},
+			expFullTrace: []string{
+				"a -> b",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := Merge{Filters: tc.filters}
+			m.Add([]string{
+				"service/a.go:10 A",
+				"google.golang.org/grpc/server.go:5 handle",
+				"runtime/asm_amd64.s:1 goexit",
+			}, "a")
+			m.Add([]string{
+				"service/b.go:20 B",
+				"testing/testing.go:1 tRunner",
+			}, "b")
+			assert.Equal(t, tc.expFullTrace, m.FullTrace())
+		})
+	}
+}
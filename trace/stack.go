@@ -3,7 +3,9 @@ package trace
 import (
 	"fmt"
 	"reflect"
+	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/go-stack/stack"
 )
@@ -22,6 +24,9 @@ type StackConfig struct {
 	PackagesShown []string
 	// TrimRuntime will remove entries from the Go runtime
 	TrimRuntime bool
+	// TrimStdlib will remove entries from the standard library, i.e. packages
+	// whose path doesn't start with a domain name
+	TrimStdlib bool
 	// FormatStack is the format for lines in the stack trace
 	// The default will print the source reference and the function name
 	FormatStack func(stack.Call) string
@@ -29,17 +34,21 @@ type StackConfig struct {
 	FormatReference func(stack.Call) string
 }
 
-func (c StackConfig) shouldKeepCall(call stack.Call) bool {
-	if c.RemoveLambdas {
-		fnName := fmt.Sprintf("%n", call)
-		if strings.Contains(fnName, ".func") {
-			return false
-		}
+// keepFrame returns true if the frame is included in stack traces.
+func (c StackConfig) keepFrame(frame runtime.Frame) bool {
+	if c.RemoveLambdas && strings.Contains(funcName(frame), ".func") {
+		return false
+	}
+	if len(c.PackagesShown) == 0 && !c.TrimStdlib {
+		return true
+	}
+	pkgName := pkgPath(frame)
+	if c.TrimStdlib && isStdlib(pkgName) {
+		return false
 	}
 	if len(c.PackagesShown) == 0 {
 		return true
 	}
-	pkgName := fmt.Sprintf("%+k", call)
 	for _, p := range c.PackagesShown {
 		if strings.HasPrefix(pkgName, p) {
 			return true
@@ -48,11 +57,17 @@ func (c StackConfig) shouldKeepCall(call stack.Call) bool {
 	return false
 }
 
-func (c StackConfig) formatStackLine(call stack.Call) string {
-	if c.FormatStack != nil {
-		return c.FormatStack(call)
-	}
-	return fmt.Sprintf("%+v %n", call, call)
+// isStdlib returns true for standard library package paths, which unlike
+// other packages don't have a dot in their first element.
+func isStdlib(pkgPath string) bool {
+	first, _, _ := strings.Cut(pkgPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// formatsCalls returns true if c has formatters, which need the calls
+// created by go-stack rather than just their frames.
+func (c StackConfig) formatsCalls() bool {
+	return c.FormatStack != nil || c.FormatReference != nil
 }
 
 func (c StackConfig) formatReference(ref stack.Call) string {
@@ -62,25 +77,191 @@ func (c StackConfig) formatReference(ref stack.Call) string {
 	return fmt.Sprintf("%+v", ref)
 }
 
+// formatFrameReference returns the source code reference of the first
+// frame, calls are the matching calls if c formatsCalls.
+func (c StackConfig) formatFrameReference(frames []runtime.Frame, calls stack.CallStack) string {
+	if c.FormatReference != nil {
+		if len(calls) == 0 {
+			return c.FormatReference(stack.Call{})
+		}
+		return c.FormatReference(calls[0])
+	}
+	if len(frames) == 0 {
+		return fileRef(runtime.Frame{})
+	}
+	return fileRef(frames[0])
+}
+
 const maxDepth = 64
 
+// callBuffer holds the buffers used to collect a stack trace,
+// they are pooled to avoid allocating them for each trace.
+type callBuffer struct {
+	pcs    []uintptr
+	frames []runtime.Frame
+}
+
+var callBuffers = sync.Pool{
+	New: func() any {
+		return &callBuffer{pcs: make([]uintptr, maxDepth)}
+	},
+}
+
+// callStack returns the frames of the calls leading to callStack, skipping
+// the first `skip` of them, so 1 starts at the caller of callStack's caller.
+// It does the same as stack.Trace, but reuses the buffers in b. The calls
+// are only collected with stack.Trace if config formatsCalls. The result is
+// only valid until b is reused.
+func (b *callBuffer) callStack(skip int, config StackConfig) ([]runtime.Frame, stack.CallStack) {
+	if config.formatsCalls() {
+		calls := stack.Trace()
+		if skip+1 >= len(calls) {
+			return nil, nil
+		}
+		calls = calls[skip+1:]
+		return b.callFrames(calls), calls
+	}
+
+	// Include the frame above the first one we want, so runtime.CallersFrames
+	// can handle the special case of runtime.sigpanic
+	n := b.callers(skip)
+	b.frames = b.frames[:0]
+	frames := runtime.CallersFrames(b.pcs[:n])
+	frame, more := frames.Next()
+	for more {
+		frame, more = frames.Next()
+		b.frames = append(b.frames, frame)
+	}
+	return b.frames, nil
+}
+
+// callStackAt returns the frames of the calls leading to pc, a program
+// counter returned by runtime.Callers, like callStack. It returns false if
+// pc isn't on the stack of the caller of callStackAt, e.g. because the
+// function it was taken in has returned.
+func (b *callBuffer) callStackAt(pc uintptr, config StackConfig) ([]runtime.Frame, stack.CallStack, bool) {
+	if config.formatsCalls() {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		calls := stack.Trace()
+		for i, c := range calls {
+			if f := c.Frame(); f.PC == frame.PC && f.Function == frame.Function {
+				return b.callFrames(calls[i:]), calls[i:], true
+			}
+		}
+		return nil, nil, false
+	}
+
+	n := b.callers(1)
+	for i, p := range b.pcs[:n] {
+		if p != pc {
+			continue
+		}
+		b.frames = b.frames[:0]
+		frames := runtime.CallersFrames(b.pcs[i:n])
+		for {
+			frame, more := frames.Next()
+			b.frames = append(b.frames, frame)
+			if !more {
+				break
+			}
+		}
+		return b.frames, nil, true
+	}
+	return nil, nil, false
+}
+
+// callFrames returns the frames of calls, which are only valid until b is
+// reused.
+func (b *callBuffer) callFrames(calls stack.CallStack) []runtime.Frame {
+	b.frames = b.frames[:0]
+	for _, c := range calls {
+		b.frames = append(b.frames, c.Frame())
+	}
+	return b.frames
+}
+
+// callers fills b.pcs with the program counters of the calls leading to the
+// caller of callers, skipping the first `skip` of them, so 0 starts at its
+// caller. It returns how many there are.
+func (b *callBuffer) callers(skip int) int {
+	for {
+		n := runtime.Callers(skip+2, b.pcs)
+		if n < len(b.pcs) {
+			return n
+		}
+		// The buffer might have been too small
+		b.pcs = make([]uintptr, 2*len(b.pcs))
+	}
+}
+
 // GetStackTrace returns a rendered stacktrace of the calling code, skipping
 // `skip` frames in the stack prior to this function
 func GetStackTrace(skip int, config StackConfig) []string {
-	var res []string
-	trace := stack.Trace()
-	if config.TrimRuntime {
-		trace = trace.TrimRuntime()
+	b := callBuffers.Get().(*callBuffer)
+	defer callBuffers.Put(b)
+
+	return config.formatStack(b.callStack(skip+1, config))
+}
+
+// GetSourceAndStackTrace returns the same as GetSourceCodeRef and
+// GetStackTrace, but only collects the calls once.
+func GetSourceAndStackTrace(skip int, config StackConfig) (string, []string) {
+	b := callBuffers.Get().(*callBuffer)
+	defer callBuffers.Put(b)
+
+	frames, calls := b.callStack(skip+1, config)
+	return config.formatFrameReference(frames, calls), config.formatStack(frames, calls)
+}
+
+// GetSourceAndStackTraceAt returns the source code reference and rendered
+// stack trace of pc, a program counter returned by runtime.Callers, e.g. for
+// libraries which record where their caller was called from:
+//
+//	var pcs [1]uintptr
+//	runtime.Callers(2, pcs[:]) // skip runtime.Callers and this function
+//	src, tr, ok := trace.GetSourceAndStackTraceAt(pcs[0], config)
+//
+// It returns false if pc isn't on the calling goroutine's stack.
+func GetSourceAndStackTraceAt(pc uintptr, config StackConfig) (string, []string, bool) {
+	b := callBuffers.Get().(*callBuffer)
+	defer callBuffers.Put(b)
+
+	frames, calls, ok := b.callStackAt(pc, config)
+	if !ok {
+		return "", nil, false
+	}
+	return config.formatFrameReference(frames, calls), config.formatStack(frames, calls), true
+}
+
+// formatStack renders the frames, calls are the matching calls if c
+// formatsCalls. The result doesn't reference the frames, so they can be
+// reused.
+func (c StackConfig) formatStack(frames []runtime.Frame, calls stack.CallStack) []string {
+	if c.TrimRuntime {
+		// Only frames at the end are removed, so they still match calls
+		frames = trimRuntime(frames)
 	}
-	for _, c := range trace[skip+1:] {
-		if !config.shouldKeepCall(c) {
+	n := len(frames)
+	if n > maxDepth {
+		n = maxDepth
+	}
+	res := make([]string, 0, n)
+	for i, frame := range frames {
+		if !c.keepFrame(frame) {
 			continue
 		}
-		res = append(res, config.formatStackLine(c))
+		if c.FormatStack != nil {
+			res = append(res, c.FormatStack(calls[i]))
+		} else {
+			res = append(res, stackLine(frame))
+		}
 		if len(res) >= maxDepth {
 			break
 		}
 	}
+	if len(res) == 0 {
+		return nil
+	}
 	return res
 }
 
@@ -88,3 +269,17 @@ func GetStackTrace(skip int, config StackConfig) []string {
 func GetSourceCodeRef(skip int, config StackConfig) string {
 	return config.formatReference(stack.Caller(skip + 1))
 }
+
+// GetSourceCodeRefAt returns the source code reference of pc, a program
+// counter returned by runtime.Callers. It returns false if pc isn't on the
+// calling goroutine's stack.
+func GetSourceCodeRefAt(pc uintptr, config StackConfig) (string, bool) {
+	b := callBuffers.Get().(*callBuffer)
+	defer callBuffers.Put(b)
+
+	frames, calls, ok := b.callStackAt(pc, config)
+	if !ok {
+		return "", false
+	}
+	return config.formatFrameReference(frames, calls), true
+}
@@ -1,12 +1,16 @@
 package trace
 
 import (
+	"fmt"
 	"net/http"
+	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/go-stack/stack"
 	"github.com/sebdah/goldie/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 //go:generate go test -update
@@ -73,3 +77,140 @@ func TestPkgPath(t *testing.T) {
 		})
 	}
 }
+
+func TestGetStackTraceMatchesGoStack(t *testing.T) {
+	// legacy is the previous implementation of GetStackTrace, using go-stack
+	legacy := func(skip int, config StackConfig) []string {
+		var res []string
+		trace := stack.Trace()
+		if config.TrimRuntime {
+			trace = trace.TrimRuntime()
+		}
+		for _, c := range trace[skip+1:] {
+			if config.RemoveLambdas && strings.Contains(fmt.Sprintf("%n", c), ".func") {
+				continue
+			}
+			pkgName := fmt.Sprintf("%+k", c)
+			if config.TrimStdlib && isStdlib(pkgName) {
+				continue
+			}
+			if len(config.PackagesShown) > 0 && !strings.HasPrefix(pkgName, config.PackagesShown[0]) {
+				continue
+			}
+			if config.FormatStack != nil {
+				res = append(res, config.FormatStack(c))
+			} else {
+				res = append(res, fmt.Sprintf("%+v %n", c, c))
+			}
+			if len(res) >= maxDepth {
+				break
+			}
+		}
+		return res
+	}
+
+	configs := []StackConfig{
+		{},
+		{TrimRuntime: true},
+		{RemoveLambdas: true, TrimRuntime: true},
+		{TrimStdlib: true},
+		{PackagesShown: []string{PackagePath(StackConfig{})}},
+		{FormatStack: func(c stack.Call) string { return fmt.Sprintf("%s %+n", c, c) }, TrimRuntime: true},
+	}
+	for _, depth := range []int{0, maxDepth - 5, 3 * maxDepth} {
+		for _, config := range configs {
+			var exp, act []string
+			recurse(depth, func() {
+				exp = legacy(1, config)
+				act = GetStackTrace(1, config)
+			})
+			require.NotEmpty(t, act)
+			require.Equal(t, exp, act)
+		}
+	}
+
+	var zero stack.Call
+	assert.Equal(t, fmt.Sprintf("%+v", zero), fileRef(runtime.Frame{}))
+	assert.Equal(t, fmt.Sprintf("%+v %n", zero, zero), stackLine(runtime.Frame{}))
+}
+
+func TestGetSourceAndStackTrace(t *testing.T) {
+	configs := []StackConfig{
+		{},
+		{TrimRuntime: true, RemoveLambdas: true},
+		{FormatReference: func(c stack.Call) string { return "ref" }},
+	}
+	for _, config := range configs {
+		var expSrc, actSrc string
+		var expTrace, actTrace []string
+		recurse(3, func() {
+			expSrc, expTrace = GetSourceCodeRef(1, config), GetStackTrace(1, config)
+			actSrc, actTrace = GetSourceAndStackTrace(1, config)
+		})
+		require.NotEmpty(t, actSrc)
+		assert.Equal(t, expSrc, actSrc)
+		require.NotEmpty(t, actTrace)
+		assert.Equal(t, expTrace, actTrace)
+	}
+}
+
+func TestGetSourceAndStackTraceAt(t *testing.T) {
+	configs := []StackConfig{
+		{},
+		{TrimRuntime: true, RemoveLambdas: true},
+		{FormatReference: func(c stack.Call) string { return fmt.Sprintf("%n:%d", c, c) }},
+	}
+	for _, config := range configs {
+		var expSrc, actSrc string
+		var expTrace, actTrace []string
+		var pc uintptr
+		recurse(3, func() {
+			pc, actSrc, actTrace, expSrc, expTrace = callAtCaller(config)
+		})
+		require.NotEmpty(t, actSrc)
+		assert.Equal(t, expSrc, actSrc)
+		require.NotEmpty(t, actTrace)
+		assert.Equal(t, expTrace, actTrace)
+
+		// Once the calls have returned pc isn't on the stack
+		_, ok := GetSourceCodeRefAt(pc, config)
+		assert.False(t, ok)
+		_, _, ok = GetSourceAndStackTraceAt(pc, config)
+		assert.False(t, ok)
+	}
+}
+
+func callAtCaller(config StackConfig) (uintptr, string, []string, string, []string) {
+	return atCaller(config)
+}
+
+// atCaller returns the trace at the pc of its caller along with the trace
+// collected by GetSourceAndStackTrace, which should be the same.
+func atCaller(config StackConfig) (pc uintptr, src string, tr []string, expSrc string, expTrace []string) {
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:])
+	src, tr, ok := GetSourceAndStackTraceAt(pcs[0], config)
+	if !ok {
+		return 0, "", nil, "", nil
+	}
+	if ref, ok := GetSourceCodeRefAt(pcs[0], config); !ok || ref != src {
+		return 0, "", nil, "", nil
+	}
+	expSrc, expTrace = GetSourceAndStackTrace(1, config)
+	return pcs[0], src, tr, expSrc, expTrace
+}
+
+func recurse(n int, f func()) {
+	if n == 0 {
+		f()
+		return
+	}
+	recurse(n-1, f)
+}
+
+func BenchmarkGetStackTrace(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = GetStackTrace(0, StackConfig{TrimRuntime: true})
+	}
+}
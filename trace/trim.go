@@ -0,0 +1,34 @@
+package trace
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+var trimPrefix atomic.Pointer[string]
+
+// SetTrimPrefix sets a path prefix, such as the module path or a build
+// directory, which is removed from every frame in a merged trace so that
+// frames read like "errors/errors.go:42 New".
+// An empty prefix leaves frames untouched.
+func SetTrimPrefix(prefix string) {
+	trimPrefix.Store(&prefix)
+}
+
+// SetTrimPrefixTesting sets the trim prefix for the duration of the test.
+func SetTrimPrefixTesting(t testing.TB, prefix string) {
+	old := trimPrefix.Load()
+	t.Cleanup(func() {
+		trimPrefix.Store(old)
+	})
+	SetTrimPrefix(prefix)
+}
+
+func trimFrame(frame string) string {
+	prefix := trimPrefix.Load()
+	if prefix == nil || *prefix == "" {
+		return frame
+	}
+	return strings.TrimPrefix(frame, *prefix)
+}
@@ -0,0 +1,67 @@
+package trace
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetTrimPrefix(t *testing.T) {
+	testCases := []struct {
+		name         string
+		prefix       string
+		expFullTrace []string
+	}{
+		{
+			name: "no prefix",
+			expFullTrace: []string{
+				"github.com/peterlabuschagne/jettison/errors/errors.go:42 New",
+				"other/pkg/main.go:10 main",
+			},
+		},
+		{
+			name:   "module prefix",
+			prefix: "github.com/peterlabuschagne/jettison/",
+			expFullTrace: []string{
+				"errors/errors.go:42 New",
+				"other/pkg/main.go:10 main",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetTrimPrefixTesting(t, tc.prefix)
+
+			var m Merge
+			m.Add([]string{
+				"github.com/peterlabuschagne/jettison/errors/errors.go:42 New",
+				"other/pkg/main.go:10 main",
+			}, "bin")
+			assert.Equal(t, tc.expFullTrace, m.FullTrace())
+		})
+	}
+}
+
+func TestSetTrimPrefixConcurrent(t *testing.T) {
+	SetTrimPrefixTesting(t, "")
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			SetTrimPrefix("github.com/")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			var m Merge
+			m.Add([]string{"github.com/org/repo/main.go:10 main"}, "bin")
+			m.FullTrace()
+		}
+	}()
+	wg.Wait()
+}